@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/vvelikodny/crawler/crawler"
+)
+
+// runDiff prints unified diffs of visible text of pages changed between two crawls.
+func runDiff(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	htmlPath := flags.String("html", "", "write HTML report to file")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: crawler diff [flags] <old.jsonl> <new.jsonl>")
+		fmt.Fprintln(flags.Output(), "crawls must be written with -text flag")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() < 2 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	previous, err := readPageRecords(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("read crawl '%s': %w", flags.Arg(0), err)
+	}
+	current, err := readPageRecords(flags.Arg(1))
+	if err != nil {
+		return fmt.Errorf("read crawl '%s': %w", flags.Arg(1), err)
+	}
+
+	diffs, err := crawler.DiffCrawls(previous, current)
+	if err != nil {
+		return err
+	}
+
+	if *htmlPath == "" {
+		return crawler.WriteDiffs(os.Stdout, diffs)
+	}
+
+	w, err := openOutput(*htmlPath)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	return crawler.WriteDiffsHTML(w, diffs)
+}
@@ -2,11 +2,17 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"sort"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -16,54 +22,316 @@ import (
 	"github.com/vvelikodny/crawler/crawler"
 )
 
+var (
+	outputPath   = flag.String("o", "", "write crawl records to file, '-' for stdout")
+	outputFormat = flag.String("format", "jsonl", "output format: jsonl or csv")
+	withLinks    = flag.Bool("links", false, "write discovered links to output along with pages (jsonl only)")
+	csvColumns   = flag.String("columns", "", "comma separated CSV columns, all columns if empty")
+	warcDir      = flag.String("warc", "", "archive fetched resources as WARC files to directory")
+	warcSize     = flag.Int64("warc-size", crawler.DefaultWARCSize>>20, "WARC file size in MB to roll over at")
+	mirrorDir    = flag.String("mirror", "", "save fetched pages and resources to directory for offline browsing")
+	graphPath    = flag.String("graph", "", "export site link graph to file")
+	graphFormat  = flag.String("graph-format", "dot", "link graph format: dot, graphml or json")
+	cacheDir     = flag.String("cache", "", "cache responses in directory and revalidate them on recrawl")
+	previousPath = flag.String("previous", "", "JSON Lines output of previous crawl to detect changed pages, use with -cache to revalidate pages")
+	changesPath  = flag.String("changes", "", "write changes since previous crawl to file")
+	pageText     = flag.Bool("text", false, "write visible text of pages to output, required by 'crawler diff'")
+	cookiesPath  = flag.String("cookies", "", "load cookies from Netscape cookies.txt file and save session back when finished")
+	reportPath   = flag.String("report", "", "write link analysis report (PageRank, click depth, orphans) to file and attach it to output records")
+	loginURL     = flag.String("login-url", "", "log in with form on page before crawling")
+	loginStatus  = flag.Int("login-success-status", 0, "status code of login form submit, before redirects, on success")
+	loginSuccess = flag.String("login-success-url", "", "URL login form submit redirects to on success")
+	loginText    = flag.String("login-success-text", "", "text on page after login form submit on success")
+	loginExpired = flag.String("login-expired-text", "", "text on page shown when session has expired")
+	userAgent    = flag.String("user-agent", "", "User-Agent header of requests")
+	acceptLang   = flag.String("accept-language", "", "Accept-Language header of requests, e.g. 'en-US,en;q=0.9'")
+	maxPages     = flag.Int64("max-pages", 0, "stop crawling after number of HTML pages fetched, 0 for no limit")
+	maxSize      = flag.Int64("max-size", 0, "stop crawling after MB downloaded, 0 for no limit")
+	maxDuration  = flag.Duration("max-duration", 0, "stop crawling after duration, e.g. 30m, 0 for no limit")
+	maxBodySize  = flag.Int64("max-body", 0, "fail responses larger than MB, 0 for no limit")
+	maxHostPages = flag.Int("max-host-pages", 0, "number of HTML pages to fetch per host, 0 for no limit")
+	perfPath     = flag.String("perf-report", "", "write performance report (slowest, largest and heaviest pages) to file")
+	perfTime     = flag.Duration("perf-max-time", crawler.DefaultPerformanceThresholds.ResponseTime, "flag pages responding longer, 0 to disable")
+	perfTTFB     = flag.Duration("perf-max-ttfb", crawler.DefaultPerformanceThresholds.TTFB, "flag pages with longer time to first byte, 0 to disable")
+	perfHTML     = flag.Int("perf-max-html", crawler.DefaultPerformanceThresholds.HTMLSize>>10, "flag pages with HTML larger than KB, 0 to disable")
+	perfWeight   = flag.Int64("perf-max-weight", crawler.DefaultPerformanceThresholds.PageWeight>>10, "flag pages with HTML and resources heavier than KB, 0 to disable")
+	seoPath      = flag.String("seo-report", "", "write SEO on-page audit findings grouped by rule to file")
+	seoTitle     = flag.Int("seo-max-title", crawler.DefaultMaxTitleLength, "report titles longer than number of characters")
+	pageMeta     = flag.Bool("meta", false, "write page metadata (lang, charset, Open Graph, Twitter card, hreflang, headings) to output")
+	showProgress = flag.Bool("progress", false, "show live crawl progress instead of logging visited pages")
+	metricsAddr  = flag.String("metrics", "", "serve Prometheus metrics on address, e.g. ':9090', at /metrics")
+	statsPath    = flag.String("stats", "", "write crawl statistics as JSON to file")
+	proxyRotate  = flag.String("proxy-rotation", "roundrobin", "proxy selection: roundrobin or sticky (same proxy for host)")
+)
+
+var (
+	// cookies seeded for start URL
+	cookies stringsFlag
+	// loginFields submitted with login form
+	loginFields stringsFlag
+	// headers of requests to all hosts
+	headers stringsFlag
+	// host scoped headers and credentials
+	hostHeaders, basicAuth, bearerTokens stringsFlag
+	// proxies to rotate
+	proxies stringsFlag
+)
+
+func init() {
+	flag.Var(&cookies, "cookie", "cookies for start URL as in Cookie header, e.g. 'session=abc; lang=en', may be repeated")
+	flag.Var(&loginFields, "login-field", "login form field as name=value, e.g. 'user=admin', may be repeated")
+	flag.Var(&headers, "header", "header of requests, e.g. 'X-Team: seo', may be repeated")
+	flag.Var(&hostHeaders, "host-header", "header of requests to host only as host=header, e.g. 'staging.velikodny.com=X-Env: staging', may be repeated")
+	flag.Var(&basicAuth, "basic-auth", "HTTP Basic auth for host as host=user:password, may be repeated")
+	flag.Var(&bearerTokens, "bearer-token", "bearer token for host as host=token, may be repeated")
+	flag.Var(&proxies, "proxy", "HTTP, HTTPS or SOCKS5 proxy URL, e.g. 'socks5://10.0.0.1:1080', may be repeated to rotate proxies")
+}
+
 func main() {
-	args := os.Args
-	if len(args) < 2 {
-		fmt.Println("usage: crawler <start-url>")
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		if err := runDiff(os.Args[2:]); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		if err := runServe(os.Args[2:]); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: crawler [flags] <start-url>")
+		fmt.Fprintln(flag.CommandLine.Output(), "       crawler diff [flags] <old.jsonl> <new.jsonl>")
+		fmt.Fprintln(flag.CommandLine.Output(), "       crawler serve [flags]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
 		os.Exit(1)
 	}
 
-	startURL := args[1]
+	startURL := flag.Arg(0)
 	url, err := urlx.Parse(startURL)
 	if err != nil {
 		fmt.Println(fmt.Errorf("parse URL '%s': %w", startURL, err))
 		os.Exit(1)
 	}
-	fmt.Println(">>", url.Hostname())
+	log.Println(">>", url.Hostname())
 
 	client := &http.Client{
 		Timeout: time.Second * 10,
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	c := crawler.New(
+	options := []crawler.Option{
 		crawler.WithContext(ctx),
 		crawler.WithClient(client),
 		crawler.WithConcurrency(5),
 		crawler.WithAllowedDomains(url.Hostname()),
-	)
+		crawler.WithMaxPages(*maxPages),
+		crawler.WithMaxBytes(*maxSize << 20),
+		crawler.WithMaxDuration(*maxDuration),
+		crawler.WithMaxPagesPerHost(*maxHostPages),
+		crawler.WithMaxBodySize(*maxBodySize << 20),
+	}
+
+	var output crawler.Output
+	if *outputPath != "" {
+		w, err := openOutput(*outputPath)
+		if err != nil {
+			fmt.Println(fmt.Errorf("open output '%s': %w", *outputPath, err))
+			os.Exit(1)
+		}
+		defer w.Close()
+
+		output, err = newOutput(w)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	if *warcDir != "" {
+		warc, err := crawler.NewWARCWriter(*warcDir, "crawler", *warcSize<<20)
+		if err != nil {
+			fmt.Println(fmt.Errorf("create WARC writer: %w", err))
+			os.Exit(1)
+		}
+		defer warc.Close()
+
+		options = append(options, crawler.WithWARC(warc))
+	}
+
+	var jar *crawler.CookieJar
+	if *cookiesPath != "" || len(cookies) > 0 {
+		if jar, err = newCookieJar(url); err != nil {
+			fmt.Println(fmt.Errorf("load cookies: %w", err))
+			os.Exit(1)
+		}
+
+		options = append(options, crawler.WithCookieJar(jar))
+	}
+
+	headerOptions, err := newHeaderOptions()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	options = append(options, headerOptions...)
+
+	if len(proxies) > 0 {
+		pool, err := newProxyPool()
+		if err != nil {
+			fmt.Println(fmt.Errorf("create proxy pool: %w", err))
+			os.Exit(1)
+		}
+
+		options = append(options, crawler.WithProxies(pool))
+	}
+
+	if *loginURL != "" {
+		login, err := newLogin(url)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		options = append(options, crawler.WithLogin(login))
+	}
+
+	if *pageText {
+		options = append(options, crawler.WithPageText())
+	}
+
+	if *pageMeta {
+		options = append(options, crawler.WithPageMeta())
+	}
+
+	if *cacheDir != "" {
+		cache, err := crawler.NewCache(*cacheDir)
+		if err != nil {
+			fmt.Println(fmt.Errorf("create cache: %w", err))
+			os.Exit(1)
+		}
+
+		options = append(options, crawler.WithCache(cache))
+	}
+
+	var changes *crawler.ChangeDetector
+	if *previousPath != "" {
+		previous, err := readPageRecords(*previousPath)
+		if err != nil {
+			fmt.Println(fmt.Errorf("read previous crawl '%s': %w", *previousPath, err))
+			os.Exit(1)
+		}
+
+		changes = crawler.NewChangeDetector(previous)
+		options = append(options, crawler.WithOutput(changes))
+	}
+
+	var perf *crawler.PerformanceAudit
+	if *perfPath != "" {
+		perf = crawler.NewPerformanceAudit(crawler.PerformanceThresholds{
+			ResponseTime: *perfTime,
+			TTFB:         *perfTTFB,
+			HTMLSize:     *perfHTML << 10,
+			PageWeight:   *perfWeight << 10,
+		}, crawler.DefaultPerformanceTop)
+		options = append(options, crawler.WithOutput(perf))
+	}
+
+	var seo *crawler.SEOAudit
+	if *seoPath != "" {
+		seo = crawler.NewSEOAudit(*seoTitle)
+		options = append(options, crawler.WithSEOAudit(seo))
+	}
+
+	var mirror *crawler.Mirror
+	if *mirrorDir != "" {
+		if mirror, err = crawler.NewMirror(*mirrorDir); err != nil {
+			fmt.Println(fmt.Errorf("create mirror: %w", err))
+			os.Exit(1)
+		}
+
+		options = append(options, crawler.WithMirror(mirror))
+	}
+
+	var graph *crawler.Graph
+	if *graphPath != "" || *reportPath != "" {
+		graph = crawler.NewGraph()
+	}
+
+	var analyzed *crawler.AnalyzedOutput
+	if *reportPath != "" {
+		// records are written to output once crawling is finished and link analysis is attached
+		var outputs []crawler.Output
+		if output != nil {
+			outputs = append(outputs, output)
+		}
+		analyzed = crawler.NewAnalyzedOutput(graph, url.String(), []string{url.Hostname()}, outputs...)
+		output = analyzed
+	} else if graph != nil {
+		options = append(options, crawler.WithOutput(graph))
+	}
+
+	if output != nil {
+		options = append(options, crawler.WithOutput(output))
+	}
+
+	c := crawler.New(options...)
 
 	// use mutex to print pages and pages links in order
 	var mux sync.Mutex
 	c.OnFetched(func(request *http.Request, response *crawler.Response) {
 		mux.Lock()
 		defer mux.Unlock()
-		log.Printf("page visited: %s\n", request.URL)
 
-		links := c.Extractor().ExtractLinks(request.URL, response)
+		links := c.Links(request, response)
 
-		for _, link := range links {
-			log.Printf("discovered on page: %s\n", link.Url.String())
+		if !*showProgress {
+			log.Printf("page visited: %s\n", request.URL)
+			for _, link := range links {
+				log.Printf("discovered on page: %s\n", link.Url.String())
+			}
 		}
 
 		for _, link := range links {
 			if !link.IsRejected() {
-				c.Visit(link.Url.String())
+				c.VisitLink(link)
 			}
 		}
 	})
 
+	handlePauseSignals(c)
+
+	if *metricsAddr != "" {
+		serveMetrics(*metricsAddr, c.Stat())
+	}
+
+	var p *progress
+	if *showProgress {
+		// records written to stdout are not mixed with progress
+		progressFile := os.Stdout
+		if *outputPath == "-" {
+			progressFile = os.Stderr
+		}
+		p = newProgress(progressFile, c, time.Second)
+		// log lines are printed above block redrawn in terminal
+		if p.tty {
+			log.SetOutput(p)
+		}
+		p.Start()
+	}
+
 	if err := c.Run(url.String()); err != nil {
 		log.Println(err)
 	}
@@ -71,13 +339,436 @@ func main() {
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
 
-	go func(chan<- os.Signal) {
+	// the first signal drains fetches in flight, the second one cancels them
+	go func(<-chan os.Signal) {
 		<-sigs
-		cancel()
+		log.Println("shutting down, interrupt again to cancel fetches in flight")
+		drainCtx, drainCancel := context.WithTimeout(context.Background(), 30*time.Second)
+		go func() {
+			<-sigs
+			drainCancel()
+		}()
+		if err := c.Shutdown(drainCtx); err != nil {
+			log.Println(err)
+		}
 	}(sigs)
 
 	c.Wait()
 
+	if p != nil {
+		p.Stop()
+		log.SetOutput(os.Stderr)
+	}
+
+	if output != nil {
+		if err := output.Close(); err != nil {
+			log.Println(err)
+		}
+	}
+
+	if mirror != nil {
+		if err := mirror.Close(); err != nil {
+			log.Println(err)
+		}
+	}
+
+	if *graphPath != "" {
+		if err := writeGraph(graph, *graphPath); err != nil {
+			log.Println(fmt.Errorf("export graph: %w", err))
+		}
+	}
+
+	if jar != nil && *cookiesPath != "" {
+		if err := saveCookies(jar); err != nil {
+			log.Println(fmt.Errorf("save cookies: %w", err))
+		}
+	}
+
+	if changes != nil {
+		if err := writeChanges(changes); err != nil {
+			log.Println(fmt.Errorf("write changes: %w", err))
+		}
+	}
+
+	if perf != nil {
+		if err := writePerformance(perf); err != nil {
+			log.Println(fmt.Errorf("write performance report: %w", err))
+		}
+	}
+
+	if seo != nil {
+		if err := writeSEO(seo); err != nil {
+			log.Println(fmt.Errorf("write SEO report: %w", err))
+		}
+	}
+
+	if analyzed != nil {
+		if err := writeReport(analyzed.Report(), *reportPath); err != nil {
+			log.Println(fmt.Errorf("write report: %w", err))
+		}
+	}
+
 	stat := c.Stat()
+	snapshot := stat.Snapshot()
 	log.Printf("Total: %d, Uniq: %d, Fecthed: %d\n", stat.TotalDiscovered(), stat.UniqDiscovered(), stat.TotalFetched())
+	log.Printf("Pages: %d, bytes: %d, redirects: %d, retries: %d\n", snapshot.Pages, snapshot.Bytes, snapshot.Redirects, snapshot.Retries)
+	log.Printf("Response time p50: %s, p95: %s, p99: %s\n", snapshot.Latency.P50, snapshot.Latency.P95, snapshot.Latency.P99)
+	fetchErrors := stat.FetchErrors()
+	phases := make([]string, 0, len(fetchErrors))
+	for phase := range fetchErrors {
+		phases = append(phases, string(phase))
+	}
+	sort.Strings(phases)
+	for _, phase := range phases {
+		log.Printf("Errors %s: %d\n", phase, fetchErrors[crawler.FetchPhase(phase)])
+	}
+	if reason := stat.StopReason(); reason != "" {
+		log.Printf("Stopped by %s budget\n", reason)
+	}
+	if len(proxies) > 0 {
+		log.Printf("Proxy errors: %d\n", stat.ProxyErrors())
+	}
+	if *cacheDir != "" {
+		log.Printf("Cache hits: %d, misses: %d\n", stat.CacheHits(), stat.CacheMisses())
+	}
+	if *statsPath != "" {
+		if err := writeStats(snapshot, *statsPath); err != nil {
+			log.Println(fmt.Errorf("write stats: %w", err))
+		}
+	}
+}
+
+// newOutput creates output of requested format.
+func newOutput(w io.Writer) (crawler.Output, error) {
+	switch *outputFormat {
+	case "jsonl":
+		return crawler.NewJSONLOutput(w, *withLinks), nil
+	case "csv":
+		var columns []crawler.CSVColumn
+		if *csvColumns != "" {
+			var err error
+			if columns, err = crawler.ParseCSVColumns(*csvColumns); err != nil {
+				return nil, err
+			}
+		}
+		return crawler.NewCSVOutput(w, columns...), nil
+	default:
+		return nil, fmt.Errorf("unknown output format '%s'", *outputFormat)
+	}
+}
+
+// writeGraph exports graph to file in requested format.
+func writeGraph(graph *crawler.Graph, path string) error {
+	write := graph.WriteDOT
+	switch *graphFormat {
+	case "dot":
+	case "graphml":
+		write = graph.WriteGraphML
+	case "json":
+		write = graph.WriteJSON
+	default:
+		return fmt.Errorf("unknown graph format '%s'", *graphFormat)
+	}
+
+	w, err := openOutput(path)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	return write(w)
+}
+
+// writeReport writes link analysis report to file.
+func writeReport(report *crawler.AnalysisReport, path string) error {
+	w, err := openOutput(path)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	return report.WriteJSON(w)
+}
+
+// serveMetrics serves crawler metrics in background, crawl goes on if listener fails.
+func serveMetrics(addr string, stat crawler.PublicStat) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", crawler.NewMetricsHandler(stat))
+
+	go func() {
+		log.Printf("serving metrics on %s/metrics\n", addr)
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Println(fmt.Errorf("serve metrics: %w", err))
+		}
+	}()
+}
+
+// writeStats writes crawl statistics as JSON to file.
+func writeStats(snapshot crawler.StatSnapshot, path string) error {
+	w, err := openOutput(path)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(snapshot)
+}
+
+// newCookieJar creates cookie jar with cookies loaded from file and seeded from flags.
+func newCookieJar(startURL *url.URL) (*crawler.CookieJar, error) {
+	jar, err := crawler.NewCookieJar()
+	if err != nil {
+		return nil, err
+	}
+
+	if *cookiesPath != "" {
+		f, err := os.Open(*cookiesPath)
+		switch {
+		case os.IsNotExist(err):
+			// file is created when session is saved
+		case err != nil:
+			return nil, err
+		default:
+			defer f.Close()
+			if err := jar.Load(f); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, cookie := range cookies {
+		request := &http.Request{Header: http.Header{"Cookie": []string{cookie}}}
+		jar.SetCookies(startURL, request.Cookies())
+	}
+
+	return jar, nil
+}
+
+// newHeaderOptions creates options setting request headers from flags.
+func newHeaderOptions() ([]crawler.Option, error) {
+	var options []crawler.Option
+	if *userAgent != "" {
+		options = append(options, crawler.WithUserAgent(*userAgent))
+	}
+	if *acceptLang != "" {
+		options = append(options, crawler.WithAcceptLanguage(*acceptLang))
+	}
+
+	for _, header := range headers {
+		key, value, err := splitFlag(header, ":")
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, crawler.WithHeader(key, strings.TrimSpace(value)))
+	}
+
+	for _, hostHeader := range hostHeaders {
+		host, header, err := splitFlag(hostHeader, "=")
+		if err != nil {
+			return nil, err
+		}
+		key, value, err := splitFlag(header, ":")
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, crawler.WithHostHeader(host, key, strings.TrimSpace(value)))
+	}
+
+	for _, auth := range basicAuth {
+		host, credentials, err := splitFlag(auth, "=")
+		if err != nil {
+			return nil, err
+		}
+		username, password, err := splitFlag(credentials, ":")
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, crawler.WithBasicAuth(host, username, password))
+	}
+
+	for _, bearer := range bearerTokens {
+		host, token, err := splitFlag(bearer, "=")
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, crawler.WithBearerToken(host, token))
+	}
+
+	return options, nil
+}
+
+// splitFlag splits flag value at the first separator, spaces around the name are trimmed.
+func splitFlag(value, sep string) (string, string, error) {
+	i := strings.Index(value, sep)
+	if i <= 0 {
+		return "", "", fmt.Errorf("'%s': expected %s separated value", value, sep)
+	}
+
+	return strings.TrimSpace(value[:i]), value[i+len(sep):], nil
+}
+
+// newProxyPool creates pool of proxies from flags.
+func newProxyPool() (*crawler.ProxyPool, error) {
+	var rotation crawler.ProxyRotation
+	switch *proxyRotate {
+	case "roundrobin":
+		rotation = crawler.RoundRobin
+	case "sticky":
+		rotation = crawler.StickyHost
+	default:
+		return nil, fmt.Errorf("unknown proxy rotation '%s'", *proxyRotate)
+	}
+
+	return crawler.NewProxyPool(proxies, rotation, crawler.DefaultProxyMaxFailures, crawler.DefaultProxyCooldown)
+}
+
+// newLogin creates login configured with flags, login URL may be relative to start URL.
+func newLogin(startURL *url.URL) (*crawler.Login, error) {
+	loginPage, err := startURL.Parse(*loginURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse login URL '%s': %w", *loginURL, err)
+	}
+
+	fields := url.Values{}
+	for _, field := range loginFields {
+		name, value, err := splitFlag(field, "=")
+		if err != nil {
+			return nil, fmt.Errorf("login field %w", err)
+		}
+		fields.Add(name, value)
+	}
+
+	return &crawler.Login{
+		URL:           loginPage.String(),
+		Fields:        fields,
+		SuccessStatus: *loginStatus,
+		SuccessURL:    *loginSuccess,
+		SuccessText:   *loginText,
+		ExpiredText:   *loginExpired,
+	}, nil
+}
+
+// saveCookies saves session cookies to file they were loaded from.
+func saveCookies(jar *crawler.CookieJar) error {
+	w, err := os.Create(*cookiesPath)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	return jar.Save(w)
+}
+
+// readPageRecords reads records of previous crawl from JSON Lines file.
+func readPageRecords(path string) ([]*crawler.PageRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return crawler.ReadPageRecords(f)
+}
+
+// writeChanges logs changes summary and writes changes report to file if requested.
+func writeChanges(changes *crawler.ChangeDetector) error {
+	if err := changes.Close(); err != nil {
+		return err
+	}
+
+	report := changes.Report()
+	log.Printf("New: %d, Changed: %d, Unchanged: %d, Removed: %d, Failed: %d\n", report.New, report.Changed, report.Unchanged, report.Removed, report.Failed)
+
+	if *changesPath == "" {
+		return nil
+	}
+
+	w, err := openOutput(*changesPath)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	return report.WriteJSON(w)
+}
+
+func writePerformance(perf *crawler.PerformanceAudit) error {
+	if err := perf.Close(); err != nil {
+		return err
+	}
+
+	report := perf.Report()
+	flagged := make([]string, 0, len(report.Flagged))
+	for flag, urls := range report.Flagged {
+		flagged = append(flagged, fmt.Sprintf("%s: %d", flag, len(urls)))
+	}
+	sort.Strings(flagged)
+	if len(flagged) == 0 {
+		flagged = append(flagged, "none")
+	}
+	log.Printf("Performance of %d pages, flagged %s\n", len(report.Pages), strings.Join(flagged, ", "))
+
+	w, err := openOutput(*perfPath)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	return report.WriteJSON(w)
+}
+
+func writeSEO(seo *crawler.SEOAudit) error {
+	if err := seo.Close(); err != nil {
+		return err
+	}
+
+	report := seo.Report()
+	rules := make([]string, 0, len(report.Rules))
+	for rule, findings := range report.Rules {
+		rules = append(rules, fmt.Sprintf("%s: %d", rule, len(findings)))
+	}
+	sort.Strings(rules)
+	if len(rules) == 0 {
+		rules = append(rules, "none")
+	}
+	log.Printf("SEO audit of %d pages, findings %s\n", report.Pages, strings.Join(rules, ", "))
+
+	w, err := openOutput(*seoPath)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	return report.WriteJSON(w)
+}
+
+// openOutput opens file to write records to, "-" stands for stdout.
+func openOutput(path string) (io.WriteCloser, error) {
+	if path == "-" {
+		return nopCloser{os.Stdout}, nil
+	}
+
+	return os.Create(path)
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// stringsFlag collects values of repeated flag.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ", ")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
 }
@@ -0,0 +1,31 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/vvelikodny/crawler/crawler"
+)
+
+// handlePauseSignals pauses crawler on SIGUSR1 and resumes it on SIGUSR2.
+func handlePauseSignals(c *crawler.Crawler) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGUSR1, syscall.SIGUSR2)
+
+	go func() {
+		for sig := range sigs {
+			if sig == syscall.SIGUSR1 {
+				log.Println("pausing, fetches in flight are finished")
+				c.Pause()
+			} else {
+				log.Println("resuming")
+				c.Resume()
+			}
+		}
+	}()
+}
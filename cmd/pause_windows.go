@@ -0,0 +1,8 @@
+package main
+
+import (
+	"github.com/vvelikodny/crawler/crawler"
+)
+
+// handlePauseSignals does nothing, there are no user signals on Windows.
+func handlePauseSignals(*crawler.Crawler) {}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vvelikodny/crawler/crawler"
+)
+
+// plainProgressEvery is number of redraw intervals between plain summaries, when output is not a terminal.
+const plainProgressEvery = 10
+
+// progress redraws crawl status block in terminal, or prints plain summary lines if output is not a terminal.
+// In terminal it's log output as well, so log lines are printed above the block instead of breaking it.
+type progress struct {
+	w        io.Writer
+	tty      bool
+	interval time.Duration
+	crawler  *crawler.Crawler
+
+	startedAt   time.Time
+	lastAt      time.Time
+	lastFetched int64
+	ticks       int
+
+	// mux guards output shared by drawing and logging
+	mux sync.Mutex
+	// block holds lines drawn last time, they are redrawn in place
+	block []string
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newProgress(f *os.File, c *crawler.Crawler, interval time.Duration) *progress {
+	return &progress{
+		w:        f,
+		tty:      isTerminal(f),
+		interval: interval,
+		crawler:  c,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start starts drawing progress in background.
+func (p *progress) Start() {
+	p.startedAt = time.Now()
+	p.lastAt = p.startedAt
+
+	go func() {
+		defer close(p.done)
+
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case now := <-ticker.C:
+				p.ticks++
+				if p.tty || p.ticks%plainProgressEvery == 0 {
+					p.draw(now)
+				}
+			case <-p.stop:
+				// final rate is average of the whole crawl
+				p.lastAt, p.lastFetched = p.startedAt, 0
+				p.draw(time.Now())
+				return
+			}
+		}
+	}()
+}
+
+// Stop draws final progress and stops drawing.
+func (p *progress) Stop() {
+	close(p.stop)
+	<-p.done
+}
+
+func (p *progress) draw(now time.Time) {
+	s := p.crawler.Stat().Snapshot()
+
+	rate := float64(0)
+	if elapsed := now.Sub(p.lastAt).Seconds(); elapsed > 0 {
+		rate = float64(s.TotalFetched-p.lastFetched) / elapsed
+	}
+	p.lastAt, p.lastFetched = now, s.TotalFetched
+
+	elapsed := now.Sub(p.startedAt).Truncate(time.Second)
+	if !p.tty {
+		io.WriteString(p.w, progressLine(s, elapsed, rate)+"\n")
+		return
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	var b strings.Builder
+	p.erase(&b)
+	p.block = progressLines(s, elapsed, rate, p.crawler.Paused())
+	p.render(&b)
+	io.WriteString(p.w, b.String())
+}
+
+// Write prints log line above status block.
+func (p *progress) Write(line []byte) (int, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	var b strings.Builder
+	p.erase(&b)
+	b.Write(line)
+	p.render(&b)
+	if _, err := io.WriteString(p.w, b.String()); err != nil {
+		return 0, err
+	}
+
+	return len(line), nil
+}
+
+// erase moves cursor to the first line of drawn block and clears screen below it.
+func (p *progress) erase(b *strings.Builder) {
+	if len(p.block) > 0 {
+		fmt.Fprintf(b, "\033[%dA", len(p.block))
+	}
+	b.WriteString("\r\033[J")
+}
+
+func (p *progress) render(b *strings.Builder) {
+	for _, line := range p.block {
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+}
+
+// progressLine formats plain summary line.
+func progressLine(s crawler.StatSnapshot, elapsed time.Duration, rate float64) string {
+	return fmt.Sprintf("%s elapsed, %d fetched (%.1f/s), %d queued, %d in flight, %d errors",
+		elapsed, s.TotalFetched, rate, s.Queued, s.InFlight, totalErrors(s.Errors))
+}
+
+// progressLines formats status block.
+func progressLines(s crawler.StatSnapshot, elapsed time.Duration, rate float64, paused bool) []string {
+	state := "crawling"
+	if paused {
+		state = "paused"
+	}
+
+	lines := []string{
+		fmt.Sprintf("%s  %s elapsed", state, elapsed),
+		fmt.Sprintf("fetched  %d (%.1f pages/s), %s", s.TotalFetched, rate, formatBytes(s.Bytes)),
+		fmt.Sprintf("queue    %d queued, %d in flight, %d discovered", s.Queued, s.InFlight, s.UniqDiscovered),
+		fmt.Sprintf("latency  p50 %s, p95 %s", s.Latency.P50.Round(time.Millisecond), s.Latency.P95.Round(time.Millisecond)),
+	}
+
+	errors := fmt.Sprintf("errors   %d", totalErrors(s.Errors))
+	if len(s.Errors) > 0 {
+		classes := make([]string, 0, len(s.Errors))
+		for class, n := range s.Errors {
+			classes = append(classes, fmt.Sprintf("%s %d", class, n))
+		}
+		sort.Strings(classes)
+		errors += " (" + strings.Join(classes, ", ") + ")"
+	}
+
+	return append(lines, errors)
+}
+
+func totalErrors(errors map[crawler.FetchPhase]int64) int64 {
+	var total int64
+	for _, n := range errors {
+		total += n
+	}
+
+	return total
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vvelikodny/crawler/crawler"
+)
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n      int64
+		output string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KB"},
+		{1536, "1.5 KB"},
+		{5 << 20, "5.0 MB"},
+		{3 << 30, "3.0 GB"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.output, formatBytes(test.n), test.n)
+	}
+}
+
+func TestProgressLines(t *testing.T) {
+	s := crawler.StatSnapshot{
+		TotalFetched:   10,
+		UniqDiscovered: 25,
+		Bytes:          2048,
+		Queued:         3,
+		InFlight:       2,
+		Errors:         map[crawler.FetchPhase]int64{crawler.PhaseHTTPStatus: 2, crawler.PhaseTimeout: 1},
+		Latency:        crawler.HistogramSnapshot{P50: 120 * time.Millisecond, P95: 1500 * time.Millisecond},
+	}
+
+	tests := []struct {
+		name   string
+		paused bool
+		lines  []string
+	}{
+		{"crawling", false, []string{
+			"crawling  1m5s elapsed",
+			"fetched  10 (2.5 pages/s), 2.0 KB",
+			"queue    3 queued, 2 in flight, 25 discovered",
+			"latency  p50 120ms, p95 1.5s",
+			"errors   3 (http_status 2, timeout 1)",
+		}},
+		{"paused", true, []string{
+			"paused  1m5s elapsed",
+			"fetched  10 (2.5 pages/s), 2.0 KB",
+			"queue    3 queued, 2 in flight, 25 discovered",
+			"latency  p50 120ms, p95 1.5s",
+			"errors   3 (http_status 2, timeout 1)",
+		}},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.lines, progressLines(s, 65*time.Second, 2.5, test.paused), test.name)
+	}
+
+	assert.Equal(t, "1m5s elapsed, 10 fetched (2.5/s), 3 queued, 2 in flight, 3 errors", progressLine(s, 65*time.Second, 2.5))
+}
+
+func TestProgress_Plain(t *testing.T) {
+	var b bytes.Buffer
+	p := &progress{w: &b, crawler: crawler.New()}
+	p.startedAt = time.Now()
+	p.lastAt = p.startedAt
+
+	p.draw(p.startedAt.Add(2 * time.Second))
+	assert.Equal(t, "2s elapsed, 0 fetched (0.0/s), 0 queued, 0 in flight, 0 errors\n", b.String())
+}
+
+func TestProgress_Write(t *testing.T) {
+	var b bytes.Buffer
+	p := &progress{w: &b, tty: true, crawler: crawler.New(), block: []string{"a", "b"}}
+
+	// log line replaces block, which is drawn again below it
+	_, err := p.Write([]byte("log line\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "\033[2A\r\033[Jlog line\na\nb\n", b.String())
+}
+
+func TestIsTerminal(t *testing.T) {
+	devNull, err := os.Open(os.DevNull)
+	assert.NoError(t, err)
+	defer devNull.Close()
+
+	assert.False(t, isTerminal(devNull))
+}
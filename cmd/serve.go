@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/vvelikodny/crawler/server"
+)
+
+// runServe runs HTTP API to manage crawl jobs until interrupted.
+func runServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", ":8080", "address to listen on")
+	dataDir := flags.String("data", "jobs", "directory to store results of jobs in")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: crawler serve [flags]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	s, err := server.New(*dataDir)
+	if err != nil {
+		return err
+	}
+
+	httpServer := &http.Server{Addr: *addr, Handler: s}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := httpServer.Shutdown(ctx); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	log.Printf("listening on %s\n", *addr)
+	if err := httpServer.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+
+	// cancel running jobs, so their results are flushed
+	s.Close()
+	return nil
+}
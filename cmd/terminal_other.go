@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd && !windows
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd,!windows
+
+package main
+
+import (
+	"os"
+)
+
+// isTerminal reports no terminal, progress is printed as plain lines.
+func isTerminal(*os.File) bool {
+	return false
+}
@@ -0,0 +1,314 @@
+package crawler
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+)
+
+const (
+	pageRankDamping    = 0.85
+	pageRankIterations = 100
+	pageRankTolerance  = 1e-9
+)
+
+// PageAnalysis holds link analysis results of a page.
+type PageAnalysis struct {
+	URL string `json:"url"`
+	// PageRank holds internal PageRank, ranks of all pages sum up to 1.
+	PageRank float64 `json:"pagerank"`
+	// ClickDepth holds the shortest number of clicks from seed page, -1 if page is unreachable.
+	ClickDepth int `json:"click_depth"`
+	Inlinks    int `json:"inlinks"`
+	Outlinks   int `json:"outlinks"`
+	// Orphan is set for pages no other page links to.
+	Orphan bool `json:"orphan"`
+}
+
+// Analyze calculates PageRank, click depth from seed page, inlinks and outlinks counts
+// and orphan status of pages. Only successfully fetched pages of allowed domains are analysed,
+// all domains are allowed if none is given. Links to other sites and to failed pages are skipped,
+// so they take no PageRank.
+func (g *Graph) Analyze(seed string, allowedDomains ...string) map[string]*PageAnalysis {
+	nodes := g.pages(allowedDomains)
+	analysis := make(map[string]*PageAnalysis, len(nodes))
+	for _, node := range nodes {
+		analysis[node.URL] = &PageAnalysis{URL: node.URL, ClickDepth: -1}
+	}
+
+	// targets holds linked pages of every analysed page
+	targets := make(map[string][]string, len(nodes))
+	for _, node := range nodes {
+		for _, edge := range g.Edges(node.URL) {
+			if _, ok := analysis[edge.To]; !ok || edge.To == edge.From {
+				continue
+			}
+			targets[edge.From] = append(targets[edge.From], edge.To)
+			analysis[edge.From].Outlinks++
+			analysis[edge.To].Inlinks++
+		}
+	}
+
+	for pageURL, page := range analysis {
+		page.Orphan = page.Inlinks == 0 && pageURL != seed
+	}
+
+	clickDepth(seed, targets, analysis)
+	pageRank(nodes, targets, analysis)
+
+	return analysis
+}
+
+// pages returns successfully fetched pages of allowed domains.
+func (g *Graph) pages(allowedDomains []string) []*GraphNode {
+	var pages []*GraphNode
+	for _, node := range g.Nodes() {
+		if node.Status < http.StatusOK || node.Status >= http.StatusMultipleChoices {
+			continue
+		}
+		if len(allowedDomains) > 0 && !hostAllowed(node.URL, allowedDomains) {
+			continue
+		}
+		pages = append(pages, node)
+	}
+
+	return pages
+}
+
+func hostAllowed(rawURL string, allowedDomains []string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+
+	for _, domain := range allowedDomains {
+		if u.Hostname() == domain {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clickDepth walks links breadth-first from seed page.
+func clickDepth(seed string, targets map[string][]string, analysis map[string]*PageAnalysis) {
+	if _, ok := analysis[seed]; !ok {
+		return
+	}
+
+	analysis[seed].ClickDepth = 0
+	queue := []string{seed}
+	for len(queue) > 0 {
+		from := queue[0]
+		queue = queue[1:]
+
+		for _, to := range targets[from] {
+			if page := analysis[to]; page.ClickDepth == -1 {
+				page.ClickDepth = analysis[from].ClickDepth + 1
+				queue = append(queue, to)
+			}
+		}
+	}
+}
+
+// pageRank calculates PageRank with power iteration, rank of pages without outlinks
+// is distributed evenly between all pages.
+func pageRank(nodes []*GraphNode, targets map[string][]string, analysis map[string]*PageAnalysis) {
+	n := len(nodes)
+	if n == 0 {
+		return
+	}
+
+	index := make(map[string]int, n)
+	for i, node := range nodes {
+		index[node.URL] = i
+	}
+
+	links := make([][]int, n)
+	for i, node := range nodes {
+		for _, to := range targets[node.URL] {
+			links[i] = append(links[i], index[to])
+		}
+	}
+
+	rank := make([]float64, n)
+	for i := range rank {
+		rank[i] = 1 / float64(n)
+	}
+
+	next := make([]float64, n)
+	for iteration := 0; iteration < pageRankIterations; iteration++ {
+		dangling := 0.0
+		for i := range rank {
+			if len(links[i]) == 0 {
+				dangling += rank[i]
+			}
+		}
+
+		base := (1-pageRankDamping)/float64(n) + pageRankDamping*dangling/float64(n)
+		for i := range next {
+			next[i] = base
+		}
+		for i, to := range links {
+			share := pageRankDamping * rank[i] / float64(len(to))
+			for _, j := range to {
+				next[j] += share
+			}
+		}
+
+		delta := 0.0
+		for i := range rank {
+			delta += math.Abs(next[i] - rank[i])
+		}
+		rank, next = next, rank
+
+		if delta < pageRankTolerance {
+			break
+		}
+	}
+
+	for i, node := range nodes {
+		analysis[node.URL].PageRank = rank[i]
+	}
+}
+
+// AnalysisReport holds link analysis of all crawled pages.
+type AnalysisReport struct {
+	Seed string `json:"seed"`
+	// Pages are sorted by PageRank, highest first.
+	Pages []*PageAnalysis `json:"pages"`
+	// Orphans holds URLs of orphan pages.
+	Orphans []string `json:"orphans"`
+	// Unreachable holds URLs of pages which can't be reached from seed page.
+	Unreachable []string `json:"unreachable"`
+	MaxDepth    int      `json:"max_depth"`
+}
+
+// NewAnalysisReport creates report from analysis of graph pages.
+func NewAnalysisReport(seed string, analysis map[string]*PageAnalysis) *AnalysisReport {
+	report := &AnalysisReport{
+		Seed:        seed,
+		Pages:       make([]*PageAnalysis, 0, len(analysis)),
+		Orphans:     make([]string, 0),
+		Unreachable: make([]string, 0),
+	}
+
+	for _, page := range analysis {
+		report.Pages = append(report.Pages, page)
+	}
+	sort.Slice(report.Pages, func(i, j int) bool {
+		if report.Pages[i].PageRank != report.Pages[j].PageRank {
+			return report.Pages[i].PageRank > report.Pages[j].PageRank
+		}
+		return report.Pages[i].URL < report.Pages[j].URL
+	})
+
+	for _, page := range report.Pages {
+		if page.Orphan {
+			report.Orphans = append(report.Orphans, page.URL)
+		}
+		if page.ClickDepth == -1 {
+			report.Unreachable = append(report.Unreachable, page.URL)
+		}
+		if page.ClickDepth > report.MaxDepth {
+			report.MaxDepth = page.ClickDepth
+		}
+	}
+
+	return report
+}
+
+// WriteJSON writes report as JSON document.
+func (r *AnalysisReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// NewAnalyzedOutput creates Output attaching link analysis of pages of allowed domains to page records.
+// Analysis is possible only when crawling is finished, so records are buffered
+// and written to outputs on Close. Records are used to build graph as well.
+func NewAnalyzedOutput(graph *Graph, seed string, allowedDomains []string, outputs ...Output) *AnalyzedOutput {
+	return &AnalyzedOutput{
+		graph:          graph,
+		seed:           seed,
+		allowedDomains: allowedDomains,
+		outputs:        outputs,
+		links:          make(map[string][]*Link),
+	}
+}
+
+// AnalyzedOutput is Output attaching link analysis to page records.
+type AnalyzedOutput struct {
+	mux            sync.Mutex
+	graph          *Graph
+	seed           string
+	allowedDomains []string
+	outputs        []Output
+	pages          []*PageRecord
+	// links holds discovered links by source page URL
+	links  map[string][]*Link
+	report *AnalysisReport
+}
+
+func (o *AnalyzedOutput) WritePage(record *PageRecord) error {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
+	o.pages = append(o.pages, record)
+	return o.graph.WritePage(record)
+}
+
+func (o *AnalyzedOutput) WriteLink(link *Link) error {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
+	o.links[link.Source] = append(o.links[link.Source], link)
+	return o.graph.WriteLink(link)
+}
+
+func (o *AnalyzedOutput) usesPageInfo() bool {
+	return needPageInfo(o.outputs)
+}
+
+// Close analyses graph, writes buffered records to outputs and closes them.
+func (o *AnalyzedOutput) Close() error {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
+	analysis := o.graph.Analyze(o.seed, o.allowedDomains...)
+	o.report = NewAnalysisReport(o.seed, analysis)
+
+	for _, output := range o.outputs {
+		for _, record := range o.pages {
+			record.Analysis = analysis[record.URL]
+			if err := output.WritePage(record); err != nil {
+				return err
+			}
+			for _, link := range o.links[record.URL] {
+				if err := output.WriteLink(link); err != nil {
+					return err
+				}
+			}
+		}
+		if err := output.Close(); err != nil {
+			return err
+		}
+	}
+	o.pages = nil
+	o.links = make(map[string][]*Link)
+
+	return nil
+}
+
+// Report returns analysis report, available after Close.
+func (o *AnalyzedOutput) Report() *AnalysisReport {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
+	return o.report
+}
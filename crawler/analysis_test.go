@@ -0,0 +1,103 @@
+package crawler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// addTestEdges adds edges between pages fetched with 200 OK.
+func addTestEdges(g *Graph, edges ...string) {
+	for _, edge := range edges {
+		urls := strings.Split(edge, "->")
+		g.AddEdge(&GraphEdge{From: urls[0], To: urls[1], Tag: "a"})
+		for _, u := range urls {
+			g.WritePage(&PageRecord{URL: u, StatusCode: http.StatusOK, ContentType: "text/html"})
+		}
+	}
+}
+
+func TestGraph_Analyze(t *testing.T) {
+	g := NewGraph()
+	addTestEdges(g, "/->/a", "/->/b", "/a->/b", "/b->/", "/b->/b", "/b->/c", "/orphan->/a")
+
+	analysis := g.Analyze("/")
+	assert.Equal(t, 5, len(analysis))
+
+	assert.Equal(t, &PageAnalysis{URL: "/", PageRank: analysis["/"].PageRank, ClickDepth: 0, Inlinks: 1, Outlinks: 2}, analysis["/"])
+	assert.Equal(t, 1, analysis["/b"].ClickDepth)
+	assert.Equal(t, 2, analysis["/b"].Inlinks)
+	assert.Equal(t, 2, analysis["/c"].ClickDepth)
+	assert.Equal(t, -1, analysis["/orphan"].ClickDepth)
+	assert.True(t, analysis["/orphan"].Orphan)
+	assert.False(t, analysis["/"].Orphan)
+
+	sum := 0.0
+	for _, page := range analysis {
+		sum += page.PageRank
+	}
+	assert.InDelta(t, 1, sum, 1e-6)
+	assert.True(t, analysis["/b"].PageRank > analysis["/a"].PageRank)
+	assert.True(t, analysis["/a"].PageRank > analysis["/orphan"].PageRank)
+
+	report := NewAnalysisReport("/", analysis)
+	assert.Equal(t, "/b", report.Pages[0].URL)
+	assert.Equal(t, []string{"/orphan"}, report.Orphans)
+	assert.Equal(t, []string{"/orphan"}, report.Unreachable)
+	assert.Equal(t, 2, report.MaxDepth)
+}
+
+func TestGraph_AnalyzeSkipsForeignAndFailedPages(t *testing.T) {
+	internal := NewGraph()
+	addTestEdges(internal, "https://velikodny.com/->https://velikodny.com/a", "https://velikodny.com/a->https://velikodny.com/")
+
+	g := NewGraph()
+	addTestEdges(g, "https://velikodny.com/->https://velikodny.com/a", "https://velikodny.com/a->https://velikodny.com/")
+	// external page is fetched when crawler allows all domains, 404 page is fetched but failed
+	addTestEdges(g, "https://velikodny.com/->https://example.com/")
+	g.AddEdge(&GraphEdge{From: "https://velikodny.com/a", To: "https://velikodny.com/missing", Tag: "a"})
+	g.WritePage(&PageRecord{URL: "https://velikodny.com/missing", StatusCode: http.StatusNotFound, ContentType: "text/html"})
+	g.AddEdge(&GraphEdge{From: "https://velikodny.com/a", To: "https://velikodny.com/never-fetched", Tag: "a"})
+
+	expected := internal.Analyze("https://velikodny.com/", "velikodny.com")
+	analysis := g.Analyze("https://velikodny.com/", "velikodny.com")
+	assert.Equal(t, 2, len(analysis))
+	for pageURL, page := range expected {
+		assert.InDelta(t, page.PageRank, analysis[pageURL].PageRank, 1e-9, pageURL)
+		assert.Equal(t, 1, analysis[pageURL].Outlinks, pageURL)
+	}
+
+	report := NewAnalysisReport("https://velikodny.com/", analysis)
+	assert.Empty(t, report.Orphans)
+	assert.Empty(t, report.Unreachable)
+}
+
+func TestAnalyzedOutput(t *testing.T) {
+	var b bytes.Buffer
+	output := NewAnalyzedOutput(NewGraph(), "https://velikodny.com/", []string{"velikodny.com"}, NewJSONLOutput(&b, true))
+
+	link := NewHrefLink(&Link{Ref: "https://velikodny.com/"}, "https://velikodny.com/a")
+	NewLinkProcessor().Process(link)
+	link.Tag = "a"
+
+	assert.NoError(t, output.WritePage(&PageRecord{URL: "https://velikodny.com/", StatusCode: http.StatusOK, ContentType: "text/html"}))
+	assert.NoError(t, output.WriteLink(link))
+	assert.NoError(t, output.WritePage(&PageRecord{URL: "https://velikodny.com/a", StatusCode: http.StatusOK, ContentType: "text/html"}))
+	assert.Equal(t, 0, b.Len())
+
+	assert.NoError(t, output.Close())
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	assert.Equal(t, 3, len(lines))
+
+	var record PageRecord
+	assert.NoError(t, json.Unmarshal([]byte(lines[2]), &record))
+	assert.Equal(t, "https://velikodny.com/a", record.URL)
+	assert.Equal(t, 1, record.Analysis.ClickDepth)
+	assert.Equal(t, 1, record.Analysis.Inlinks)
+
+	assert.Equal(t, 2, len(output.Report().Pages))
+}
@@ -0,0 +1,204 @@
+package crawler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+var (
+	ErrBudgetExceeded = errors.New("crawl budget exceeded")
+)
+
+// Budget names crawl limit.
+type Budget string
+
+const (
+	BudgetPages     Budget = "pages"
+	BudgetBytes     Budget = "bytes"
+	BudgetDuration  Budget = "duration"
+	BudgetHostPages Budget = "host_pages"
+)
+
+// BudgetError is returned for URLs refused because crawl budget ran out, it matches ErrBudgetExceeded.
+type BudgetError struct {
+	Budget Budget
+	// Limit of budget, nanoseconds for duration.
+	Limit int64
+	// Host is set for host pages budget.
+	Host string
+}
+
+func (e *BudgetError) Error() string {
+	switch e.Budget {
+	case BudgetDuration:
+		return fmt.Sprintf("%s: %s of %s", ErrBudgetExceeded, e.Budget, time.Duration(e.Limit))
+	case BudgetHostPages:
+		return fmt.Sprintf("%s: %d pages of host %s", ErrBudgetExceeded, e.Limit, e.Host)
+	default:
+		return fmt.Sprintf("%s: %d %s", ErrBudgetExceeded, e.Limit, e.Budget)
+	}
+}
+
+func (e *BudgetError) Is(target error) bool {
+	return target == ErrBudgetExceeded
+}
+
+// budget tracks crawl limits, zero limit means no limit. Pages budgets count HTML pages fetched
+// successfully, so failed fetches and other resources, e.g. images, don't use them up. Fetcher
+// acquires page of budget before fetch and releases it after, so no more than max pages are fetched:
+// if the rest of budget is held by fetches in flight, fetcher waits for them, as they may fail.
+// Once pages, bytes or duration budget runs out, it's recorded as stop reason, new URLs are refused
+// and queued URLs are dropped.
+type budget struct {
+	maxPages     int64
+	maxBytes     int64
+	maxDuration  time.Duration
+	maxHostPages int
+	stat         DetailedStat
+
+	mux sync.Mutex
+	// released is broadcast when fetch releases budget
+	released *sync.Cond
+	// pages and hostPages count fetched pages, fetching and hostFetching count fetches in flight
+	pages        int64
+	fetching     int64
+	hostPages    map[string]int
+	hostFetching map[string]int
+	bytes        int64
+	startedAt    time.Time
+	// exceeded is budget which stopped crawl
+	exceeded *BudgetError
+	// now is used to check duration, replaced in tests
+	now func() time.Time
+}
+
+func newBudget(cfg *Config, stat DetailedStat) *budget {
+	b := &budget{
+		maxPages:     cfg.maxPages,
+		maxBytes:     cfg.maxBytes,
+		maxDuration:  cfg.maxDuration,
+		maxHostPages: cfg.maxHostPages,
+		stat:         stat,
+		hostPages:    make(map[string]int),
+		hostFetching: make(map[string]int),
+		now:          time.Now,
+	}
+	b.released = sync.NewCond(&b.mux)
+
+	return b
+}
+
+// start starts duration budget clock.
+func (b *budget) start() {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	if b.startedAt.IsZero() {
+		b.startedAt = b.now()
+	}
+}
+
+// admit checks URL of host can be queued.
+func (b *budget) admit(host string) error {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	if err := b.check(); err != nil {
+		return err
+	}
+	if b.maxHostPages > 0 && b.hostPages[host] >= b.maxHostPages {
+		return &BudgetError{Budget: BudgetHostPages, Limit: int64(b.maxHostPages), Host: host}
+	}
+
+	return nil
+}
+
+// acquire checks queued URL of host can be fetched and holds page of budget until release.
+func (b *budget) acquire(host string) error {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	for {
+		if err := b.check(); err != nil {
+			return err
+		}
+
+		pagesFull := b.maxPages > 0 && b.pages+b.fetching >= b.maxPages
+		hostFull := b.maxHostPages > 0 && b.hostPages[host]+b.hostFetching[host] >= b.maxHostPages
+		switch {
+		case !pagesFull && !hostFull:
+			b.fetching++
+			b.hostFetching[host]++
+			return nil
+		case pagesFull && b.fetching == 0:
+			return b.exceed(&BudgetError{Budget: BudgetPages, Limit: b.maxPages})
+		case hostFull && b.hostFetching[host] == 0:
+			return &BudgetError{Budget: BudgetHostPages, Limit: int64(b.maxHostPages), Host: host}
+		}
+
+		b.released.Wait()
+	}
+}
+
+// addPage counts page of host fetched by fetch holding budget.
+func (b *budget) addPage(host string) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	b.pages++
+	b.hostPages[host]++
+	if b.maxPages > 0 && b.pages >= b.maxPages {
+		b.exceed(&BudgetError{Budget: BudgetPages, Limit: b.maxPages})
+	}
+}
+
+// release releases budget held by fetch of host.
+func (b *budget) release(host string) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	b.fetching--
+	b.hostFetching[host]--
+	b.released.Broadcast()
+}
+
+// addBytes counts downloaded bytes.
+func (b *budget) addBytes(n int) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	b.bytes += int64(n)
+	if b.maxBytes > 0 && b.bytes >= b.maxBytes {
+		b.exceed(&BudgetError{Budget: BudgetBytes, Limit: b.maxBytes})
+	}
+}
+
+// check returns budget which stopped crawl, duration budget is checked first.
+func (b *budget) check() error {
+	if b.timeUp() {
+		b.exceed(&BudgetError{Budget: BudgetDuration, Limit: int64(b.maxDuration)})
+	}
+
+	if b.exceeded == nil {
+		return nil
+	}
+	return b.exceeded
+}
+
+func (b *budget) timeUp() bool {
+	return b.maxDuration > 0 && !b.startedAt.IsZero() && b.now().Sub(b.startedAt) >= b.maxDuration
+}
+
+// exceed records budget which stopped crawl, the first one is kept and returned.
+func (b *budget) exceed(err *BudgetError) error {
+	if b.exceeded == nil {
+		b.exceeded = err
+		b.stat.SetStopReason(string(err.Budget))
+		log.Println(err)
+	}
+
+	return b.exceeded
+}
@@ -0,0 +1,127 @@
+package crawler
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudget(t *testing.T) {
+	stat := NewStat()
+	b := newBudget(&Config{maxPages: 3, maxHostPages: 2, maxDuration: time.Minute}, stat)
+	now := time.Now()
+	b.now = func() time.Time { return now }
+	b.start()
+
+	// fetch which is not a page doesn't use budget
+	assert.NoError(t, b.admit("a.velikodny.com"))
+	assert.NoError(t, b.acquire("a.velikodny.com"))
+	b.release("a.velikodny.com")
+
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, b.acquire("a.velikodny.com"))
+		b.addPage("a.velikodny.com")
+		b.release("a.velikodny.com")
+	}
+
+	// host cap refuses host pages only and doesn't stop crawl
+	err := b.admit("a.velikodny.com")
+	assert.True(t, errors.Is(err, ErrBudgetExceeded))
+	assert.Equal(t, &BudgetError{Budget: BudgetHostPages, Limit: 2, Host: "a.velikodny.com"}, err)
+	assert.Equal(t, &BudgetError{Budget: BudgetHostPages, Limit: 2, Host: "a.velikodny.com"}, b.acquire("a.velikodny.com"))
+	assert.Equal(t, "", stat.StopReason())
+
+	assert.NoError(t, b.admit("b.velikodny.com"))
+	assert.NoError(t, b.acquire("b.velikodny.com"))
+	b.addPage("b.velikodny.com")
+	b.release("b.velikodny.com")
+
+	err = b.admit("b.velikodny.com")
+	assert.Equal(t, &BudgetError{Budget: BudgetPages, Limit: 3}, err)
+	assert.Equal(t, "crawl budget exceeded: 3 pages", err.Error())
+	assert.Equal(t, "pages", stat.StopReason())
+	// queued pages are dropped
+	assert.Equal(t, err, b.acquire("c.velikodny.com"))
+
+	now = now.Add(time.Minute)
+	// the first budget is kept as stop reason
+	assert.Equal(t, err, b.acquire("c.velikodny.com"))
+	assert.Equal(t, "pages", stat.StopReason())
+}
+
+func TestBudget_AcquireWaits(t *testing.T) {
+	b := newBudget(&Config{maxPages: 1}, NewStat())
+
+	assert.NoError(t, b.acquire("velikodny.com"))
+
+	// the last page of budget is held by fetch in flight, so the next fetch waits for it
+	acquired := make(chan error)
+	go func() {
+		acquired <- b.acquire("velikodny.com")
+	}()
+
+	// fetch in flight fails, so waiting fetch gets the page
+	b.release("velikodny.com")
+	assert.NoError(t, <-acquired)
+
+	go func() {
+		acquired <- b.acquire("velikodny.com")
+	}()
+
+	b.addPage("velikodny.com")
+	b.release("velikodny.com")
+	assert.Equal(t, &BudgetError{Budget: BudgetPages, Limit: 1}, <-acquired)
+}
+
+func TestCrawler_WithMaxPages(t *testing.T) {
+	server := newTestSite()
+	defer server.Close()
+
+	output := &recordsOutput{records: make(map[string]*PageRecord)}
+	var refused []error
+	crawler := New(WithConcurrency(1), WithOutput(output), WithMaxPages(2))
+	crawler.OnFetched(func(request *http.Request, response *Response) {
+		for _, link := range crawler.Links(request, response) {
+			if err := crawler.VisitLink(link); err != nil {
+				refused = append(refused, err)
+			}
+		}
+	})
+
+	assert.NoError(t, crawler.Run(server.URL+"/"))
+	crawler.Wait()
+
+	// not found page doesn't use budget, so two pages are fetched
+	pages := 0
+	for _, record := range output.records {
+		if record.Error == "" && isHTML(record.ContentType) {
+			pages++
+		}
+	}
+	assert.Equal(t, 2, pages)
+	assert.Equal(t, int64(2), crawler.Stat().PagesCount())
+	assert.Equal(t, string(BudgetPages), crawler.Stat().StopReason())
+	assert.True(t, errors.Is(refused[len(refused)-1], ErrBudgetExceeded))
+}
+
+func TestCrawler_WithMaxBytes(t *testing.T) {
+	server := newTestSite()
+	defer server.Close()
+
+	output := &recordsOutput{records: make(map[string]*PageRecord)}
+	crawler := New(WithConcurrency(1), WithOutput(output), WithMaxBytes(100))
+	crawler.OnFetched(func(request *http.Request, response *Response) {
+		for _, link := range crawler.Links(request, response) {
+			crawler.VisitLink(link)
+		}
+	})
+
+	assert.NoError(t, crawler.Run(server.URL+"/"))
+	crawler.Wait()
+
+	assert.Equal(t, 1, len(output.records))
+	assert.Equal(t, string(BudgetBytes), crawler.Stat().StopReason())
+}
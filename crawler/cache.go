@@ -0,0 +1,326 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CacheStatus is outcome of request passed through Cache transport.
+type CacheStatus string
+
+const (
+	// CacheHit is response served from cache, either fresh or revalidated with 304 Not Modified.
+	CacheHit CacheStatus = "HIT"
+	// CacheMiss is response fetched from origin.
+	CacheMiss CacheStatus = "MISS"
+)
+
+type cacheStatusKey struct{}
+
+// WithCacheStatus returns context recording status of requests sent with it through Cache transport
+// to status. Responses are not marked, so cache status never leaks to headers of archived or handled responses.
+func WithCacheStatus(ctx context.Context, status *CacheStatus) context.Context {
+	return context.WithValue(ctx, cacheStatusKey{}, status)
+}
+
+func setCacheStatus(req *http.Request, status CacheStatus) {
+	if s, ok := req.Context().Value(cacheStatusKey{}).(*CacheStatus); ok {
+		*s = status
+	}
+}
+
+// Cache stores fetched responses on disk and revalidates them with conditional requests,
+// following RFC 7234 for a private cache: fresh responses are served without request,
+// stale responses with ETag or Last-Modified validators are revalidated with
+// If-None-Match and If-Modified-Since, and 304 Not Modified is served from cache as 200 OK.
+// Only successful GET responses are cached, Vary is not supported.
+type Cache struct {
+	dir string
+	// now is used to check freshness, replaced in tests
+	now func() time.Time
+}
+
+type cacheEntry struct {
+	URL      string      `json:"url"`
+	Proto    string      `json:"proto"`
+	Header   http.Header `json:"header"`
+	StoredAt time.Time   `json:"stored_at"`
+}
+
+// NewCache creates Cache storing responses in dir.
+func NewCache(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &Cache{dir: dir, now: time.Now}, nil
+}
+
+// Transport wraps next transport with cache, cache status of request is recorded with WithCacheStatus.
+func (cache *Cache) Transport(next http.RoundTripper) http.RoundTripper {
+	return cache.transport(next, 0)
+}
+
+// transport wraps next transport with cache, bodies larger than maxBodySize are not cached, zero means no limit.
+func (cache *Cache) transport(next http.RoundTripper, maxBodySize int64) http.RoundTripper {
+	return &cacheTransport{cache: cache, next: next, maxBodySize: maxBodySize}
+}
+
+type cacheTransport struct {
+	cache       *Cache
+	next        http.RoundTripper
+	maxBodySize int64
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || hasDirective(req.Header, "no-store") {
+		return t.next.RoundTrip(req)
+	}
+
+	entry, body, err := t.cache.load(req.URL.String())
+	if err != nil {
+		return t.miss(req)
+	}
+
+	if !hasDirective(req.Header, "no-cache") && entry.fresh(t.cache.now()) {
+		setCacheStatus(req, CacheHit)
+		return entry.response(req, body), nil
+	}
+
+	etag, lastModified := entry.Header.Get("ETag"), entry.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return t.miss(req)
+	}
+
+	conditional := req.Clone(req.Context())
+	if etag != "" {
+		conditional.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		conditional.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	resp, err := t.next.RoundTrip(conditional)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusNotModified {
+		return t.store(req, resp)
+	}
+	resp.Body.Close()
+
+	// 304 carries updated headers of stored response
+	for key, values := range resp.Header {
+		if key != "Content-Length" {
+			entry.Header[key] = values
+		}
+	}
+	entry.StoredAt = t.cache.now()
+	// stored body is still valid, entry is revalidated again next time if it can't be updated
+	if err := t.cache.save(entry, nil); err != nil {
+		log.Println(fmt.Errorf("cache %s: %w", entry.URL, err))
+	}
+
+	setCacheStatus(req, CacheHit)
+	return entry.response(req, body), nil
+}
+
+func (t *cacheTransport) miss(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.store(req, resp)
+}
+
+// store saves successful response to cache and marks it as cache miss. Response is returned
+// uncached if its body is larger than limit or cache can't be written.
+func (t *cacheTransport) store(req *http.Request, resp *http.Response) (*http.Response, error) {
+	setCacheStatus(req, CacheMiss)
+	if resp.StatusCode != http.StatusOK || hasDirective(resp.Header, "no-store") {
+		return resp, nil
+	}
+	if t.maxBodySize > 0 && resp.ContentLength > t.maxBodySize {
+		return resp, nil
+	}
+
+	reader := io.Reader(resp.Body)
+	if t.maxBodySize > 0 {
+		reader = io.LimitReader(resp.Body, t.maxBodySize+1)
+	}
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	// oversized body is passed on unread, so reader of response rejects it
+	if t.maxBodySize > 0 && int64(len(body)) > t.maxBodySize {
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	entry := &cacheEntry{
+		URL:      req.URL.String(),
+		Proto:    resp.Proto,
+		Header:   resp.Header.Clone(),
+		StoredAt: t.cache.now(),
+	}
+	if err := t.cache.save(entry, body); err != nil {
+		log.Println(fmt.Errorf("cache %s: %w", entry.URL, err))
+	}
+
+	return resp, nil
+}
+
+// fresh checks whether stored response can be served without revalidation.
+func (e *cacheEntry) fresh(now time.Time) bool {
+	if hasDirective(e.Header, "no-cache") {
+		return false
+	}
+
+	age := now.Sub(e.StoredAt)
+	if seconds, err := strconv.Atoi(e.Header.Get("Age")); err == nil {
+		age += time.Duration(seconds) * time.Second
+	}
+
+	if maxAge, ok := directive(e.Header, "max-age"); ok {
+		seconds, err := strconv.Atoi(maxAge)
+		return err == nil && age < time.Duration(seconds)*time.Second
+	}
+
+	if expires := e.Header.Get("Expires"); expires != "" {
+		expiresAt, err := http.ParseTime(expires)
+		if err != nil {
+			return false
+		}
+		date, err := http.ParseTime(e.Header.Get("Date"))
+		if err != nil {
+			date = e.StoredAt
+		}
+		return age < expiresAt.Sub(date)
+	}
+
+	// no heuristic freshness, responses without explicit lifetime are always revalidated
+	return false
+}
+
+func (e *cacheEntry) response(req *http.Request, body []byte) *http.Response {
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         e.Proto,
+		ProtoMajor:    1,
+		Header:        e.Header.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+func (cache *Cache) path(url string) string {
+	sum := md5.Sum([]byte(url))
+	return filepath.Join(cache.dir, hex.EncodeToString(sum[:]))
+}
+
+func (cache *Cache) load(url string) (*cacheEntry, []byte, error) {
+	path := cache.path(url)
+
+	b, err := ioutil.ReadFile(path + ".json")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return nil, nil, err
+	}
+
+	body, err := ioutil.ReadFile(path + ".body")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &entry, body, nil
+}
+
+// save writes entry and body to cache, body is not written if nil.
+func (cache *Cache) save(entry *cacheEntry, body []byte) error {
+	path := cache.path(entry.URL)
+
+	if body != nil {
+		if err := writeFileAtomic(path+".body", body); err != nil {
+			return err
+		}
+	}
+
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path+".json", b)
+}
+
+// writeFileAtomic writes file with rename, so concurrent readers never see partially written file.
+func writeFileAtomic(path string, b []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// directive returns value of Cache-Control directive.
+func directive(header http.Header, name string) (string, bool) {
+	for _, value := range header.Values("Cache-Control") {
+		for _, d := range strings.Split(value, ",") {
+			d = strings.TrimSpace(d)
+			key, val := d, ""
+			if i := strings.IndexByte(d, '='); i >= 0 {
+				key, val = d[:i], strings.Trim(d[i+1:], `"`)
+			}
+			if strings.EqualFold(key, name) {
+				return val, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+func hasDirective(header http.Header, name string) bool {
+	_, ok := directive(header, name)
+	return ok
+}
@@ -0,0 +1,213 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache_Revalidate(t *testing.T) {
+	var requests, notModified int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<a href="/">home</a>`)
+	}))
+	defer server.Close()
+
+	dir, remove := tempDir(t)
+	defer remove()
+	cache, err := NewCache(dir)
+	assert.NoError(t, err)
+	client := &http.Client{Transport: cache.Transport(http.DefaultTransport)}
+
+	for i, status := range []CacheStatus{CacheMiss, CacheHit, CacheHit} {
+		var cacheStatus CacheStatus
+		req, _ := http.NewRequestWithContext(WithCacheStatus(context.Background(), &cacheStatus), http.MethodGet, server.URL, nil)
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode, i)
+		assert.Equal(t, status, cacheStatus, i)
+		assert.Equal(t, `<a href="/">home</a>`, string(body), i)
+		assert.Equal(t, "text/html", resp.Header.Get("Content-Type"), i)
+	}
+	assert.Equal(t, int32(3), requests)
+	assert.Equal(t, int32(2), notModified)
+}
+
+func TestCache_Fresh(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Cache-Control", "public, max-age=60")
+		fmt.Fprint(w, "fresh")
+	}))
+	defer server.Close()
+
+	dir, remove := tempDir(t)
+	defer remove()
+	cache, err := NewCache(dir)
+	assert.NoError(t, err)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	client := &http.Client{Transport: cache.Transport(http.DefaultTransport)}
+
+	get := func() CacheStatus {
+		var cacheStatus CacheStatus
+		req, _ := http.NewRequestWithContext(WithCacheStatus(context.Background(), &cacheStatus), http.MethodGet, server.URL, nil)
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		return cacheStatus
+	}
+
+	assert.Equal(t, CacheMiss, get())
+	assert.Equal(t, CacheHit, get())
+	assert.Equal(t, int32(1), requests)
+
+	// stale response without validators is fetched again
+	now = now.Add(time.Minute)
+	assert.Equal(t, CacheMiss, get())
+	assert.Equal(t, int32(2), requests)
+}
+
+func TestCrawler_WithCache(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-Modified-Since") != "" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Last-Modified", "Mon, 18 Oct 2021 10:00:00 GMT")
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<p>page</p>`)
+	}))
+	defer server.Close()
+
+	dir, remove := tempDir(t)
+	defer remove()
+	cache, err := NewCache(dir)
+	assert.NoError(t, err)
+
+	for _, hit := range []bool{false, true} {
+		var fetched []byte
+		output := &recordsOutput{records: make(map[string]*PageRecord)}
+		crawler := New(WithConcurrency(1), WithCache(cache), WithOutput(output))
+		crawler.OnFetched(func(request *http.Request, response *Response) {
+			fetched = response.Body
+		})
+		assert.NoError(t, crawler.Run(server.URL))
+		crawler.Wait()
+
+		assert.Equal(t, "<p>page</p>", string(fetched))
+		assert.Equal(t, hit, output.records[server.URL].CacheHit)
+		if hit {
+			assert.Equal(t, int64(1), crawler.Stat().CacheHits())
+		} else {
+			assert.Equal(t, int64(1), crawler.Stat().CacheMisses())
+		}
+	}
+}
+
+func TestCache_SaveFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, "origin")
+	}))
+	defer server.Close()
+
+	dir, remove := tempDir(t)
+	defer remove()
+	cache, err := NewCache(filepath.Join(dir, "cache"))
+	assert.NoError(t, err)
+	// cache can't be written once its directory is gone
+	assert.NoError(t, os.RemoveAll(filepath.Join(dir, "cache")))
+	client := &http.Client{Transport: cache.Transport(http.DefaultTransport)}
+
+	resp, err := client.Get(server.URL)
+	assert.NoError(t, err)
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "origin", string(body))
+}
+
+func TestCrawler_WithCacheMaxBodySize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "text/html")
+		// chunked response has no Content-Length, so body size is known only by reading it
+		w.(http.Flusher).Flush()
+		fmt.Fprint(w, strings.Repeat("x", 100))
+	}))
+	defer server.Close()
+
+	dir, remove := tempDir(t)
+	defer remove()
+	cache, err := NewCache(dir)
+	assert.NoError(t, err)
+
+	var fetchErr *FetchError
+	crawler := New(WithConcurrency(1), WithCache(cache), WithMaxBodySize(10))
+	crawler.OnError(func(err *FetchError) {
+		fetchErr = err
+	})
+	assert.NoError(t, crawler.Run(server.URL))
+	crawler.Wait()
+
+	assert.True(t, errors.Is(fetchErr, ErrTooLarge))
+	files, _ := ioutil.ReadDir(dir)
+	assert.Empty(t, files)
+}
+
+func TestCrawler_WithCacheWARC(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<p>page</p>`)
+	}))
+	defer server.Close()
+
+	cacheDir, removeCache := tempDir(t)
+	defer removeCache()
+	cache, err := NewCache(cacheDir)
+	assert.NoError(t, err)
+	dir, remove := tempDir(t)
+	defer remove()
+
+	for _, prefix := range []string{"live", "hit"} {
+		warc, err := NewWARCWriter(dir, prefix, 0)
+		assert.NoError(t, err)
+		crawler := New(WithConcurrency(1), WithCache(cache), WithWARC(warc))
+		assert.NoError(t, crawler.Run(server.URL))
+		crawler.Wait()
+		assert.NoError(t, warc.Close())
+	}
+
+	live, _ := filepath.Glob(filepath.Join(dir, "live-*.warc.gz"))
+	assert.Equal(t, 1, len(live))
+	content := readWARC(t, live[0])
+	assert.Contains(t, content, "WARC-Type: response\r\n")
+
+	// fresh cache hit is not fetched live, so it's not archived and no file is started
+	hit, _ := filepath.Glob(filepath.Join(dir, "hit-*.warc.gz"))
+	assert.Empty(t, hit)
+}
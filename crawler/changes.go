@@ -0,0 +1,196 @@
+package crawler
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// ChangeStatus classifies page compared to previous crawl.
+type ChangeStatus string
+
+const (
+	PageNew       ChangeStatus = "new"
+	PageChanged   ChangeStatus = "changed"
+	PageUnchanged ChangeStatus = "unchanged"
+	PageRemoved   ChangeStatus = "removed"
+	// PageFailed is page which got no response this time, e.g. because of network error,
+	// so it can't be compared to previous crawl.
+	PageFailed ChangeStatus = "failed"
+)
+
+// PageChange describes how page changed since previous crawl.
+type PageChange struct {
+	URL            string       `json:"url"`
+	Status         ChangeStatus `json:"status"`
+	PreviousStatus int          `json:"previous_status,omitempty"`
+	CurrentStatus  int          `json:"current_status,omitempty"`
+	PreviousMd5    string       `json:"previous_md5,omitempty"`
+	CurrentMd5     string       `json:"current_md5,omitempty"`
+}
+
+// ChangeReport holds changes of all pages between two crawls.
+type ChangeReport struct {
+	New       int `json:"new"`
+	Changed   int `json:"changed"`
+	Unchanged int `json:"unchanged"`
+	Removed   int `json:"removed"`
+	Failed    int `json:"failed"`
+	// Pages are sorted by URL.
+	Pages []*PageChange `json:"pages"`
+}
+
+// ReadPageRecords reads page records written by JSON Lines output, link records are skipped.
+func ReadPageRecords(r io.Reader) ([]*PageRecord, error) {
+	var records []*PageRecord
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var record jsonlPage
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, err
+		}
+		if record.Type == jsonlPageType {
+			records = append(records, record.PageRecord)
+		}
+	}
+
+	return records, scanner.Err()
+}
+
+// CompareCrawls classifies pages of current crawl compared to previous one.
+// Page is changed if its content hash or status code differs. Page is removed
+// if it was not crawled this time or responds with 404 Not Found or 410 Gone now.
+// Page is failed if request got no response this time, so network errors are not reported as changes.
+func CompareCrawls(previous, current []*PageRecord) *ChangeReport {
+	before := pagesByURL(previous)
+	after := pagesByURL(current)
+
+	report := &ChangeReport{Pages: make([]*PageChange, 0)}
+	for url, record := range after {
+		change := &PageChange{URL: url, CurrentStatus: record.StatusCode, CurrentMd5: record.ContentMd5}
+
+		old, ok := before[url]
+		if ok {
+			change.PreviousStatus = old.StatusCode
+			change.PreviousMd5 = old.ContentMd5
+		}
+
+		switch {
+		case failed(record):
+			change.Status = PageFailed
+		case ok && gone(record) && !gone(old):
+			change.Status = PageRemoved
+		case !ok:
+			change.Status = PageNew
+		case old.StatusCode != record.StatusCode || old.ContentMd5 != record.ContentMd5:
+			change.Status = PageChanged
+		default:
+			change.Status = PageUnchanged
+		}
+		report.add(change)
+	}
+
+	for url, old := range before {
+		if _, ok := after[url]; !ok {
+			report.add(&PageChange{URL: url, Status: PageRemoved, PreviousStatus: old.StatusCode, PreviousMd5: old.ContentMd5})
+		}
+	}
+
+	sort.Slice(report.Pages, func(i, j int) bool {
+		return report.Pages[i].URL < report.Pages[j].URL
+	})
+
+	return report
+}
+
+// WriteJSON writes report as JSON document.
+func (r *ChangeReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func (r *ChangeReport) add(change *PageChange) {
+	switch change.Status {
+	case PageNew:
+		r.New++
+	case PageChanged:
+		r.Changed++
+	case PageUnchanged:
+		r.Unchanged++
+	case PageRemoved:
+		r.Removed++
+	case PageFailed:
+		r.Failed++
+	}
+	r.Pages = append(r.Pages, change)
+}
+
+func pagesByURL(records []*PageRecord) map[string]*PageRecord {
+	pages := make(map[string]*PageRecord, len(records))
+	for _, record := range records {
+		pages[record.URL] = record
+	}
+
+	return pages
+}
+
+// failed checks request of page got no response.
+func failed(record *PageRecord) bool {
+	return record.StatusCode == 0 && record.Error != ""
+}
+
+func gone(record *PageRecord) bool {
+	return record.StatusCode == http.StatusNotFound || record.StatusCode == http.StatusGone
+}
+
+// NewChangeDetector creates Output comparing records of current crawl to previous crawl.
+func NewChangeDetector(previous []*PageRecord) *ChangeDetector {
+	return &ChangeDetector{previous: previous}
+}
+
+// ChangeDetector is Output collecting page records to compare them to previous crawl.
+type ChangeDetector struct {
+	mux      sync.Mutex
+	previous []*PageRecord
+	current  []*PageRecord
+	report   *ChangeReport
+}
+
+func (d *ChangeDetector) WritePage(record *PageRecord) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	d.current = append(d.current, record)
+	return nil
+}
+
+func (d *ChangeDetector) usesPageInfo() bool {
+	return false
+}
+
+func (d *ChangeDetector) WriteLink(*Link) error {
+	return nil
+}
+
+// Close compares crawls.
+func (d *ChangeDetector) Close() error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	d.report = CompareCrawls(d.previous, d.current)
+	return nil
+}
+
+// Report returns changes report, available after Close.
+func (d *ChangeDetector) Report() *ChangeReport {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	return d.report
+}
@@ -0,0 +1,72 @@
+package crawler
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadPageRecords(t *testing.T) {
+	var b bytes.Buffer
+	output := NewJSONLOutput(&b, true)
+	output.WritePage(&PageRecord{URL: "https://velikodny.com/", StatusCode: 200, ContentMd5: "1"})
+	output.WriteLink(&Link{Source: "https://velikodny.com/", Ref: "https://velikodny.com/a"})
+	output.WritePage(&PageRecord{URL: "https://velikodny.com/a", StatusCode: 200, ContentMd5: "2"})
+
+	records, err := ReadPageRecords(&b)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(records))
+	assert.Equal(t, "https://velikodny.com/a", records[1].URL)
+	assert.Equal(t, "2", records[1].ContentMd5)
+}
+
+func TestCompareCrawls(t *testing.T) {
+	previous := []*PageRecord{
+		{URL: "/", StatusCode: 200, ContentMd5: "1"},
+		{URL: "/changed", StatusCode: 200, ContentMd5: "2"},
+		{URL: "/deleted", StatusCode: 200, ContentMd5: "3"},
+		{URL: "/dropped", StatusCode: 200, ContentMd5: "4"},
+		{URL: "/broken", StatusCode: 200, ContentMd5: "5"},
+		{URL: "/timeout", StatusCode: 200, ContentMd5: "7"},
+	}
+	current := []*PageRecord{
+		{URL: "/", StatusCode: 200, ContentMd5: "1"},
+		{URL: "/changed", StatusCode: 200, ContentMd5: "22"},
+		{URL: "/deleted", StatusCode: 404, ContentMd5: "33"},
+		{URL: "/broken", StatusCode: 500, ContentMd5: "55"},
+		{URL: "/new", StatusCode: 200, ContentMd5: "6"},
+		{URL: "/timeout", Error: "context deadline exceeded"},
+		{URL: "/new-timeout", Error: "connection refused"},
+	}
+
+	detector := NewChangeDetector(previous)
+	for _, record := range current {
+		assert.NoError(t, detector.WritePage(record))
+	}
+	assert.NoError(t, detector.Close())
+	report := detector.Report()
+
+	statuses := make(map[string]ChangeStatus)
+	for _, change := range report.Pages {
+		statuses[change.URL] = change.Status
+	}
+	assert.Equal(t, map[string]ChangeStatus{
+		"/":        PageUnchanged,
+		"/changed": PageChanged,
+		"/deleted": PageRemoved,
+		"/dropped": PageRemoved,
+		"/broken":  PageChanged,
+		"/new":     PageNew,
+		// network errors are not changes
+		"/timeout":     PageFailed,
+		"/new-timeout": PageFailed,
+	}, statuses)
+
+	assert.Equal(t, 1, report.New)
+	assert.Equal(t, 2, report.Changed)
+	assert.Equal(t, 1, report.Unchanged)
+	assert.Equal(t, 2, report.Removed)
+	assert.Equal(t, 2, report.Failed)
+	assert.Equal(t, "/", report.Pages[0].URL)
+}
@@ -0,0 +1,252 @@
+package crawler
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	cookiesTxtHeader   = "# Netscape HTTP Cookie File"
+	cookiesTxtHTTPOnly = "#HttpOnly_"
+)
+
+var (
+	ErrMalformedCookie = errors.New("malformed cookie")
+)
+
+// CookieJar is http.CookieJar scoping cookies with public suffix list, so a site
+// can't set cookies for a whole TLD. Unlike cookiejar.Jar it keeps track of stored
+// cookies, so session can be saved and loaded in Netscape cookies.txt format.
+type CookieJar struct {
+	jar *cookiejar.Jar
+	mux sync.Mutex
+	// cookies holds stored cookies by domain, path and name
+	cookies map[string]*jarCookie
+}
+
+// jarCookie is cookie with resolved scope as written to cookies.txt.
+type jarCookie struct {
+	Domain            string
+	IncludeSubdomains bool
+	Path              string
+	Secure            bool
+	HttpOnly          bool
+	Expires           time.Time
+	Name              string
+	Value             string
+}
+
+// NewCookieJar creates empty CookieJar.
+func NewCookieJar() (*CookieJar, error) {
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		return nil, err
+	}
+
+	return &CookieJar{
+		jar:     jar,
+		cookies: make(map[string]*jarCookie),
+	}, nil
+}
+
+// SetCookies implements http.CookieJar.
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	now := time.Now()
+	for _, cookie := range cookies {
+		c := &jarCookie{
+			Domain:   strings.ToLower(u.Hostname()),
+			Path:     cookie.Path,
+			Secure:   cookie.Secure,
+			HttpOnly: cookie.HttpOnly,
+			Expires:  cookie.Expires,
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+		}
+		if cookie.Domain != "" {
+			c.Domain = strings.ToLower(strings.TrimPrefix(cookie.Domain, "."))
+			c.IncludeSubdomains = true
+		}
+		if c.Path == "" || !strings.HasPrefix(c.Path, "/") {
+			c.Path = defaultCookiePath(u.Path)
+		}
+		if cookie.MaxAge > 0 {
+			c.Expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+		}
+
+		key := c.Domain + ";" + c.Path + ";" + c.Name
+		if cookie.MaxAge < 0 || (!c.Expires.IsZero() && !c.Expires.After(now)) {
+			delete(j.cookies, key)
+			continue
+		}
+
+		// cookie rejected by jar, e.g. set for public suffix or another domain
+		if !j.accepted(c) {
+			continue
+		}
+		j.cookies[key] = c
+	}
+}
+
+// Cookies implements http.CookieJar.
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// accepted checks jar returns cookie for its own scope.
+func (j *CookieJar) accepted(c *jarCookie) bool {
+	for _, cookie := range j.jar.Cookies(c.url()) {
+		if cookie.Name == c.Name && cookie.Value == c.Value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Load reads cookies in Netscape cookies.txt format, expired cookies are skipped.
+func (j *CookieJar) Load(r io.Reader) error {
+	now := time.Now()
+	scanner := bufio.NewScanner(r)
+
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		httpOnly := strings.HasPrefix(line, cookiesTxtHTTPOnly)
+		line = strings.TrimPrefix(line, cookiesTxtHTTPOnly)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return fmt.Errorf("line %d: %w", n, ErrMalformedCookie)
+		}
+
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return fmt.Errorf("line %d: expires: %w", n, ErrMalformedCookie)
+		}
+
+		c := &jarCookie{
+			Domain:            strings.TrimPrefix(fields[0], "."),
+			IncludeSubdomains: strings.EqualFold(fields[1], "TRUE"),
+			Path:              fields[2],
+			Secure:            strings.EqualFold(fields[3], "TRUE"),
+			HttpOnly:          httpOnly,
+			Name:              fields[5],
+			Value:             fields[6],
+		}
+		if expires != 0 {
+			c.Expires = time.Unix(expires, 0)
+			if c.Expires.Before(now) {
+				continue
+			}
+		}
+
+		j.SetCookies(c.url(), []*http.Cookie{c.cookie()})
+	}
+
+	return scanner.Err()
+}
+
+// Save writes cookies in Netscape cookies.txt format.
+func (j *CookieJar) Save(w io.Writer) error {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	keys := make([]string, 0, len(j.cookies))
+	for key := range j.cookies {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, cookiesTxtHeader)
+
+	now := time.Now()
+	for _, key := range keys {
+		c := j.cookies[key]
+		if !c.Expires.IsZero() && c.Expires.Before(now) {
+			continue
+		}
+
+		domain := c.Domain
+		if c.IncludeSubdomains {
+			domain = "." + domain
+		}
+		if c.HttpOnly {
+			domain = cookiesTxtHTTPOnly + domain
+		}
+
+		var expires int64
+		if !c.Expires.IsZero() {
+			expires = c.Expires.Unix()
+		}
+
+		fmt.Fprintf(bw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			domain, boolTxt(c.IncludeSubdomains), c.Path, boolTxt(c.Secure), expires, c.Name, c.Value)
+	}
+
+	return bw.Flush()
+}
+
+// url returns URL cookie is sent to.
+func (c *jarCookie) url() *url.URL {
+	scheme := "http"
+	if c.Secure {
+		scheme = "https"
+	}
+
+	return &url.URL{Scheme: scheme, Host: c.Domain, Path: c.Path}
+}
+
+func (c *jarCookie) cookie() *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     c.Name,
+		Value:    c.Value,
+		Path:     c.Path,
+		Secure:   c.Secure,
+		HttpOnly: c.HttpOnly,
+		Expires:  c.Expires,
+	}
+	if c.IncludeSubdomains {
+		cookie.Domain = c.Domain
+	}
+
+	return cookie
+}
+
+// defaultCookiePath returns cookie path for URL path as defined by RFC 6265 section 5.1.4.
+func defaultCookiePath(urlPath string) string {
+	if urlPath == "" || urlPath[0] != '/' {
+		return "/"
+	}
+
+	return path.Dir(urlPath)
+}
+
+func boolTxt(b bool) string {
+	if b {
+		return "TRUE"
+	}
+
+	return "FALSE"
+}
@@ -0,0 +1,92 @@
+package crawler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCookieJar_PublicSuffix(t *testing.T) {
+	jar, err := NewCookieJar()
+	assert.NoError(t, err)
+
+	u, _ := url.Parse("https://www.velikodny.co.uk/")
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "tld", Value: "1", Domain: "co.uk"},
+		{Name: "site", Value: "2", Domain: "velikodny.co.uk"},
+	})
+
+	other, _ := url.Parse("https://other.co.uk/")
+	assert.Equal(t, 0, len(jar.Cookies(other)))
+
+	sub, _ := url.Parse("https://blog.velikodny.co.uk/")
+	cookies := jar.Cookies(sub)
+	assert.Equal(t, 1, len(cookies))
+	assert.Equal(t, "site", cookies[0].Name)
+
+	var b bytes.Buffer
+	assert.NoError(t, jar.Save(&b))
+	assert.Equal(t, cookiesTxtHeader+"\n.velikodny.co.uk\tTRUE\t/\tFALSE\t0\tsite\t2\n", b.String())
+}
+
+func TestCookieJar_LoadSave(t *testing.T) {
+	expires := time.Now().Add(time.Hour).Unix()
+	txt := fmt.Sprintf(`# Netscape HTTP Cookie File
+velikodny.com	FALSE	/	TRUE	%d	session	abc
+#HttpOnly_.velikodny.com	TRUE	/admin	FALSE	0	admin	1
+velikodny.com	FALSE	/	FALSE	1	expired	1
+`, expires)
+
+	jar, err := NewCookieJar()
+	assert.NoError(t, err)
+	assert.NoError(t, jar.Load(strings.NewReader(txt)))
+
+	u, _ := url.Parse("https://velikodny.com/admin/users")
+	cookies := jar.Cookies(u)
+	assert.Equal(t, 2, len(cookies))
+
+	plain, _ := url.Parse("http://www.velikodny.com/")
+	assert.Equal(t, 0, len(jar.Cookies(plain)))
+
+	var b bytes.Buffer
+	assert.NoError(t, jar.Save(&b))
+	assert.Equal(t, fmt.Sprintf(`# Netscape HTTP Cookie File
+velikodny.com	FALSE	/	TRUE	%d	session	abc
+#HttpOnly_.velikodny.com	TRUE	/admin	FALSE	0	admin	1
+`, expires), b.String())
+
+	assert.Error(t, jar.Load(strings.NewReader("velikodny.com\tFALSE\t/\n")))
+}
+
+func TestCrawler_WithCookieJar(t *testing.T) {
+	var sessions []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+		http.Redirect(w, r, "/home", http.StatusFound)
+	})
+	mux.HandleFunc("/home", func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie("session"); err == nil {
+			sessions = append(sessions, cookie.Value)
+		}
+		w.Header().Set("Content-Type", "text/html")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	jar, err := NewCookieJar()
+	assert.NoError(t, err)
+
+	crawler := New(WithConcurrency(1), WithCookieJar(jar))
+	assert.NoError(t, crawler.Run(server.URL+"/login"))
+	crawler.Wait()
+
+	assert.Equal(t, []string{"abc"}, sessions)
+}
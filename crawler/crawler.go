@@ -10,17 +10,25 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
 )
 
+// DefaultConcurrency is number of concurrent fetchers used if none is set.
+const DefaultConcurrency = 5
+
+// maxBodyPrealloc limits buffer preallocated for response body by Content-Length.
+const maxBodyPrealloc = 8 << 20
+
 var (
 	ErrEmptyURL         = errors.New("empty URL")
 	ErrNotAllowedDomain = errors.New("domain not allowed")
 	ErrAlreadyCrawled   = errors.New("already crawled")
+	ErrLinkRejected     = errors.New("link rejected")
+	ErrCrawlerStopped   = errors.New("crawler stopped")
 )
 
 // New .
@@ -28,18 +36,65 @@ func New(options ...Option) *Crawler {
 	c := &Crawler{
 		context: context.Background(),
 		cfg: &Config{
-			concurrency: 5,
+			concurrency: DefaultConcurrency,
 		},
 		client:           &http.Client{Timeout: time.Second},
 		stat:             NewStat(),
 		uniqCrawledLinks: make(map[string]struct{}),
+		depths:           make(map[string]int),
+		headers:          newRequestHeaders(),
+		stopped:          make(chan struct{}),
 	}
 
 	for _, opt := range options {
 		opt(c)
 	}
 
+	c.context, c.cancel = context.WithCancel(c.context)
+	c.fetchersLimit = initChanCapacity(c.cfg.concurrency)
+
+	// proxies wrap base transport, so cached responses are served without proxy
+	if c.proxies != nil {
+		c.client = wrapTransport(c.client, func(next http.RoundTripper) http.RoundTripper {
+			transport, err := c.proxies.Transport(next)
+			if err != nil {
+				// requests must not bypass proxies, so they fail
+				log.Println(err)
+				return &errTransport{err: &ProxyError{Err: err}}
+			}
+			return transport
+		})
+	}
+
+	if c.cache != nil {
+		c.client = wrapTransport(c.client, func(next http.RoundTripper) http.RoundTripper {
+			return c.cache.transport(next, c.cfg.maxBodySize)
+		})
+	}
+
+	if !c.headers.empty() {
+		c.client = wrapTransport(c.client, c.headers.Transport)
+	}
+
+	// login session is kept in cookies
+	if c.login != nil && c.jar == nil {
+		jar, err := NewCookieJar()
+		if err != nil {
+			log.Println(err)
+		} else {
+			c.jar = jar
+		}
+	}
+
+	if c.jar != nil {
+		client := *c.client
+		client.Jar = c.jar
+		c.client = &client
+	}
+
 	c.extractor = NewExtractor(c.cfg.allowedDomains...)
+	c.pageInfo = needPageInfo(c.outputs)
+	c.budget = newBudget(c.cfg, c.stat)
 
 	return c
 }
@@ -48,6 +103,17 @@ func New(options ...Option) *Crawler {
 type Config struct {
 	concurrency    int
 	allowedDomains []string
+	// pageText adds visible text of HTML pages to page records
+	pageText bool
+	// pageMeta extracts metadata of HTML pages to Response.Meta
+	pageMeta bool
+	// crawl budgets, zero means no limit
+	maxPages     int64
+	maxBytes     int64
+	maxDuration  time.Duration
+	maxHostPages int
+	// maxBodySize limits response body size, zero means no limit
+	maxBodySize int64
 }
 
 type Response struct {
@@ -56,11 +122,36 @@ type Response struct {
 	ContentLength int
 	Header        http.Header
 	Body          []byte
+	// FinalURL is the URL response was served from after following redirects.
+	FinalURL string
+	// Depth is the number of links followed from the start URL.
+	Depth int
+	// Duration is the time spent to fetch response.
+	Duration time.Duration
+	// Timing is breakdown of Duration by request phases.
+	Timing Timing
+	// Meta is metadata of HTML page, set if crawler runs WithPageMeta.
+	Meta *PageMeta
+	// CacheHit is set if response was served from cache, fresh or revalidated, so its timings don't measure fetching page.
+	CacheHit bool
+
+	// request used to fetch response
+	request *http.Request
+	// links discovered on page, extracted once by Crawler.Links
+	links []*Link
+}
+
+// Md5 returns hash of response body.
+func (r *Response) Md5() string {
+	md5v := md5.Sum(r.Body)
+	return hex.EncodeToString(md5v[:])
 }
 
 type Crawler struct {
 	// Crawler context to manage cancellation of crawling process.
 	context context.Context
+	// cancel cancels fetches in flight on Shutdown timeout
+	cancel context.CancelFunc
 	// cfg holds Crawler configuration parameters
 	cfg *Config
 	// client used to fetch pages
@@ -68,33 +159,196 @@ type Crawler struct {
 	// limits of fetchers count
 	fetchersLimit chan struct{}
 	// collect Crawler statistics
-	stat Stat
+	stat DetailedStat
 	// extractor
 	extractor Extractor
 	// run handler then new content loaded
 	onFetchedHandler []func(request *http.Request, response *Response)
+	// run handler then fetch fails
+	onErrorHandler []func(err *FetchError)
+	// outputs receive records of fetched pages and discovered links
+	outputs []Output
+	// pageInfo is set if some output uses title, description or canonical URL of records
+	pageInfo bool
+	// warc archives fetched resources
+	warc *WARCWriter
+	// mirror saves fetched resources for offline browsing
+	mirror *Mirror
+	// budget limits crawl
+	budget *budget
+	// proxies to send requests through
+	proxies *ProxyPool
+	// cache stores fetched responses to revalidate them on recrawl
+	cache *Cache
+	// jar holds session cookies shared by all fetchers
+	jar http.CookieJar
+	// headers added to every request
+	headers *requestHeaders
+	// login authenticates crawler before crawling
+	login *Login
+	// loginSession counts successful logins, guarded by loginMux
+	loginSession int
+	// protectedSession is session pages answered 401 in right after logging in, guarded by loginMux
+	protectedSession int
+	loginMux         sync.Mutex
+	// resumed is closed on Resume, nil if crawler is not paused, guarded by pauseMux
+	resumed  chan struct{}
+	pauseMux sync.Mutex
 	// in-mem crawled links holder
 	uniqCrawledLinks map[string]struct{}
+	// depth of each crawled URL, guarded by linksMux
+	depths map[string]int
 	//
 	linksMux sync.Mutex
 	// wg holds all fetchers goroutines
 	wg sync.WaitGroup
+	// started is set by Start, guarded by startMux
+	started  bool
+	startMux sync.Mutex
+	// stopped is closed on Shutdown, isStopped is guarded by stopMux
+	stopped   chan struct{}
+	isStopped bool
+	stopMux   sync.Mutex
 }
 
-// Run runs crawler from startRawURL.
+// Start starts crawling session: logs in if login is configured and starts duration budget.
+// Start is called by Run, only the first successful call has effect.
+func (c *Crawler) Start() error {
+	c.startMux.Lock()
+	defer c.startMux.Unlock()
+
+	if c.stopping() {
+		return ErrCrawlerStopped
+	}
+	if c.started {
+		return nil
+	}
+
+	if c.login != nil {
+		if err := c.authenticate(c.context); err != nil {
+			return err
+		}
+	}
+
+	c.budget.start()
+	c.started = true
+	return nil
+}
+
+// Run starts crawler and adds startRawURL to crawler queue.
+// Run may be called again after Wait to crawl more URLs.
 func (c *Crawler) Run(startRawURL string) error {
-	return c.fetch(c.context, startRawURL, http.MethodGet)
+	if err := c.Start(); err != nil {
+		return err
+	}
+
+	return c.fetch(c.context, startRawURL, http.MethodGet, 0)
 }
 
 // Visit add url to crawler queue to crawl.
 func (c *Crawler) Visit(url string) error {
-	return c.fetch(c.context, url, http.MethodGet)
+	return c.fetch(c.context, url, http.MethodGet, 0)
+}
+
+// VisitLink add link discovered on fetched page to crawler queue,
+// one level deeper than the page it was discovered on.
+func (c *Crawler) VisitLink(link *Link) error {
+	if link.IsRejected() {
+		c.stat.AddTotalDiscovered()
+		c.stat.AddSkipped(SkipRejected)
+		return fmt.Errorf("link '%s': %s: %w", link.RawRef, link.Error, ErrLinkRejected)
+	}
+
+	return c.fetch(c.context, link.Url.String(), http.MethodGet, c.depthOf(link.Source)+1)
 }
 
 // Wait waits while all fetchers exit.
 func (c *Crawler) Wait() {
 	c.wg.Wait()
-	close(c.fetchersLimit)
+}
+
+// Shutdown stops crawler: new URLs are refused with ErrCrawlerStopped, queued URLs are dropped
+// and fetches in flight are finished. If ctx is done before fetches finish, they are cancelled
+// and ctx error is returned. Crawler can't be started again after Shutdown.
+func (c *Crawler) Shutdown(ctx context.Context) error {
+	c.stopMux.Lock()
+	if !c.isStopped {
+		c.isStopped = true
+		close(c.stopped)
+	}
+	c.stopMux.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		c.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (c *Crawler) stopping() bool {
+	c.stopMux.Lock()
+	defer c.stopMux.Unlock()
+
+	return c.isStopped
+}
+
+// Pause stops fetchers from taking new URLs, fetches in flight are finished.
+// Queued URLs are kept and fetched after Resume, Wait blocks while crawler is paused.
+func (c *Crawler) Pause() {
+	c.pauseMux.Lock()
+	defer c.pauseMux.Unlock()
+
+	if c.resumed == nil {
+		c.resumed = make(chan struct{})
+	}
+}
+
+// Resume resumes paused crawler.
+func (c *Crawler) Resume() {
+	c.pauseMux.Lock()
+	defer c.pauseMux.Unlock()
+
+	if c.resumed != nil {
+		close(c.resumed)
+		c.resumed = nil
+	}
+}
+
+// Paused checks crawler is paused.
+func (c *Crawler) Paused() bool {
+	c.pauseMux.Lock()
+	defer c.pauseMux.Unlock()
+
+	return c.resumed != nil
+}
+
+// waitResumed waits while crawler is paused, false is returned if crawler is stopped.
+func (c *Crawler) waitResumed() bool {
+	c.pauseMux.Lock()
+	resumed := c.resumed
+	c.pauseMux.Unlock()
+
+	if resumed == nil {
+		return true
+	}
+
+	select {
+	case <-resumed:
+		return true
+	case <-c.stopped:
+		return false
+	case <-c.context.Done():
+		return false
+	}
 }
 
 // Stat returns crawler statistic.
@@ -106,11 +360,31 @@ func (c *Crawler) OnFetched(handler func(request *http.Request, response *Respon
 	c.onFetchedHandler = append(c.onFetchedHandler, handler)
 }
 
+// OnError adds handler run when fetch of URL fails.
+func (c *Crawler) OnError(handler func(err *FetchError)) {
+	c.onErrorHandler = append(c.onErrorHandler, handler)
+}
+
 func (c *Crawler) Extractor() Extractor {
 	return c.extractor
 }
 
-func (c *Crawler) fetch(ctx context.Context, rawURL string, method string) error {
+// Links returns links discovered on fetched page. Links are extracted once per response,
+// so handlers and outputs share the same result.
+func (c *Crawler) Links(request *http.Request, response *Response) []*Link {
+	if response.links == nil {
+		links := c.extractor.ExtractLinks(request.URL, response)
+		if links == nil {
+			// page without links is not extracted again
+			links = []*Link{}
+		}
+		response.links = links
+	}
+
+	return response.links
+}
+
+func (c *Crawler) fetch(ctx context.Context, rawURL string, method string, depth int) error {
 	parsedURL, err := url.Parse(rawURL)
 	if err != nil {
 		return err
@@ -118,27 +392,68 @@ func (c *Crawler) fetch(ctx context.Context, rawURL string, method string) error
 
 	c.stat.AddTotalDiscovered()
 
+	// wg is added under stopMux, so Shutdown never waits for fetchers added after it
+	c.stopMux.Lock()
+	defer c.stopMux.Unlock()
+	if c.isStopped {
+		c.stat.AddSkipped(SkipStopped)
+		return ErrCrawlerStopped
+	}
+
 	if err := c.shouldBeProcessed(rawURL, parsedURL); err != nil {
+		c.stat.AddSkipped(skipReason(err))
 		return err
 	}
+	c.setDepth(rawURL, depth)
 
 	c.wg.Add(1)
+	c.stat.AddQueued(1)
 	go func(wg *sync.WaitGroup) {
-		defer func() {
-			wg.Done()
-			if c.context.Err() == nil {
-				c.fetchersLimit <- struct{}{}
-			}
-		}()
+		defer wg.Done()
 
 		select {
 		// get fetcher from pool
 		case <-c.fetchersLimit:
+		case <-c.stopped:
+			c.dropQueued(SkipStopped)
+			return
 		case <-c.context.Done():
+			c.dropQueued(SkipStopped)
+			return
+		}
+		defer func() {
+			c.fetchersLimit <- struct{}{}
+		}()
+
+		if !c.waitResumed() {
+			c.dropQueued(SkipStopped)
 			return
 		}
 
-		if err := c.fetchResource(ctx, rawURL, method); err != nil {
+		// budget may run out while URL is queued
+		host := strings.ToLower(parsedURL.Host)
+		if err := c.budget.acquire(host); err != nil {
+			c.dropQueued(SkipBudget)
+			return
+		}
+		defer c.budget.release(host)
+
+		// crawler may be stopped while URL is queued
+		select {
+		case <-c.stopped:
+			c.dropQueued(SkipStopped)
+			return
+		default:
+		}
+
+		c.stat.AddQueued(-1)
+		c.stat.AddInFlight(1)
+		defer c.stat.AddInFlight(-1)
+
+		response, err := c.fetchResource(ctx, rawURL, method, depth)
+		c.writeOutputs(rawURL, depth, response, err)
+		if err != nil {
+			c.onError(err)
 			log.Println(err)
 			return
 		}
@@ -148,51 +463,206 @@ func (c *Crawler) fetch(ctx context.Context, rawURL string, method string) error
 	return nil
 }
 
-func (c *Crawler) fetchResource(ctx context.Context, url string, method string) error {
-	request, err := http.NewRequestWithContext(ctx, method, url, nil)
-	if err != nil {
-		return err
+// dropQueued counts queued URL dropped without fetching.
+func (c *Crawler) dropQueued(reason SkipReason) {
+	c.stat.AddQueued(-1)
+	c.stat.AddSkipped(reason)
+}
+
+func (c *Crawler) fetchResource(ctx context.Context, url string, method string, depth int) (*Response, error) {
+	start := time.Now()
+	trace := &requestTrace{}
+	ctx = httptrace.WithClientTrace(ctx, trace.clientTrace())
+	var cacheStatus CacheStatus
+	if c.cache != nil {
+		ctx = WithCacheStatus(ctx, &cacheStatus)
+	}
+	request, resp, body, err := c.do(ctx, url, method)
+	if resp == nil {
+		if errors.Is(err, ErrProxy) {
+			c.stat.AddProxyError()
+		}
+		return nil, &FetchError{URL: url, Phase: requestPhase(err), Err: err}
+	}
+	c.budget.addBytes(len(body))
+	c.stat.AddRedirects(redirects(resp))
+
+	switch cacheStatus {
+	case CacheHit:
+		c.stat.AddCacheHit()
+	case CacheMiss:
+		c.stat.AddCacheMiss()
 	}
 
-	resp, err := c.client.Do(request)
+	response := &Response{
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Header:      resp.Header,
+		FinalURL:    resp.Request.URL.String(),
+		Depth:       depth,
+		Duration:    time.Since(start),
+		Timing:      trace.done(time.Now()),
+		CacheHit:    cacheStatus == CacheHit,
+		request:     request,
+	}
+	c.stat.AddResponse(strings.ToLower(request.URL.Host), resp.StatusCode, response.ContentType, len(body), response.Duration)
+	c.stat.AddTiming(response.Timing)
 	if err != nil {
-		return err
+		var fetchErr *FetchError
+		if !errors.As(err, &fetchErr) {
+			err = &FetchError{URL: url, Phase: bodyPhase(err), StatusCode: resp.StatusCode, Err: err}
+		}
+		return response, err
+	}
+
+	response.ContentLength = len(body)
+	response.Body = body
+
+	// archive exchange and its redirects before status check, so error responses are archived as well,
+	// cache hits were not fetched live, so they are not archived
+	if c.warc != nil && cacheStatus != CacheHit {
+		if err := c.warc.WriteExchange(resp, response.Body); err != nil {
+			log.Println(err)
+		}
 	}
-	defer func() {
-		resp.Body.Close()
-	}()
 
 	// skip if status != OK
 	// to simplify we skip other codes like 201, 302 etc.
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("url: %s, status: %d", request.URL, resp.StatusCode)
+		return response, &FetchError{URL: url, Phase: PhaseHTTPStatus, StatusCode: resp.StatusCode, Err: ErrHTTPStatus}
+	}
+
+	if c.mirror != nil {
+		if err := c.mirror.Save(url, response); err != nil {
+			log.Println(err)
+		}
+	}
+
+	// simple web crawler process HTML pages only
+	if !isHTML(response.ContentType) {
+		return response, nil
+	}
+
+	if c.cfg.pageMeta {
+		response.Meta = ExtractPageMeta(response)
+	}
+
+	c.budget.addPage(strings.ToLower(request.URL.Host))
+	c.stat.AddPage()
+	c.onFetched(request, response)
+
+	return response, nil
+}
+
+// do sends request and reads response body. If login is configured and response
+// shows session has expired, crawler logs in again and retries request once.
+func (c *Crawler) do(ctx context.Context, url string, method string) (*http.Request, *http.Response, []byte, error) {
+	for retried := false; ; retried = true {
+		var session int
+		if c.login != nil {
+			session = c.session()
+		}
+
+		request, err := http.NewRequestWithContext(ctx, method, url, nil)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		resp, err := c.client.Do(request)
+		if err != nil {
+			return request, nil, nil, err
+		}
+
+		body, err := readBody(resp, c.cfg.maxBodySize)
+		if err != nil || c.login == nil {
+			return request, resp, body, err
+		}
+		if retried {
+			// page answering 401 in fresh session is protected, session hasn't expired
+			if resp.StatusCode == http.StatusUnauthorized {
+				c.protect(session)
+			}
+			return request, resp, body, err
+		}
+		if !c.unauthorized(session, resp) && !c.login.expired(resp, body) {
+			return request, resp, body, err
+		}
+
+		if err := c.reauthenticate(ctx, session); err != nil {
+			return request, resp, body, &FetchError{URL: url, Phase: PhaseLogin, StatusCode: resp.StatusCode, Err: err}
+		}
+		c.stat.AddRetry()
+	}
+}
+
+// readBody reads and closes response body, ErrTooLarge is returned if body is larger than maxSize.
+func readBody(resp *http.Response, maxSize int64) ([]byte, error) {
+	defer func() {
+		resp.Body.Close()
+	}()
+
+	if maxSize > 0 && resp.ContentLength > maxSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrTooLarge, resp.ContentLength)
 	}
 
+	// preallocation is bounded, so bogus Content-Length doesn't exhaust memory
 	var b bytes.Buffer
-	contentLength, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
-	if err != nil {
-		b.Grow(int(contentLength))
+	if resp.ContentLength > 0 && resp.ContentLength <= maxBodyPrealloc {
+		b.Grow(int(resp.ContentLength))
 	}
 
-	if _, err := io.Copy(&b, resp.Body); err != nil {
-		return err
+	body := io.Reader(resp.Body)
+	if maxSize > 0 {
+		body = io.LimitReader(resp.Body, maxSize+1)
+	}
+	if _, err := io.Copy(&b, body); err != nil {
+		return b.Bytes(), err
 	}
 
-	contentType := resp.Header.Get("Content-Type")
-	// simple web crawler process HTML pages only
-	if !strings.Contains(contentType, "text/html") {
-		return nil
+	if maxSize > 0 && int64(b.Len()) > maxSize {
+		return b.Bytes()[:maxSize], fmt.Errorf("%w: more than %d bytes", ErrTooLarge, maxSize)
+	}
+
+	return b.Bytes(), nil
+}
+
+func (c *Crawler) writeOutputs(rawURL string, depth int, response *Response, fetchErr error) {
+	if len(c.outputs) == 0 {
+		return
 	}
 
-	c.onFetched(request, &Response{
-		StatusCode:    resp.StatusCode,
-		ContentType:   contentType,
-		ContentLength: b.Len(),
-		Header:        resp.Header,
-		Body:          b.Bytes(),
-	})
+	var links []*Link
+	if fetchErr == nil && isHTML(response.ContentType) {
+		links = c.Links(response.request, response)
+	}
 
-	return nil
+	record := newPageRecord(rawURL, depth, response, links, fetchErr, c.pageInfo)
+	if c.cfg.pageText && fetchErr == nil && isHTML(response.ContentType) {
+		record.Text = VisibleText(response.Body)
+	}
+	for _, output := range c.outputs {
+		if err := output.WritePage(record); err != nil {
+			log.Println(err)
+		}
+		for _, link := range links {
+			if err := output.WriteLink(link); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+}
+
+// onError counts fetch error by phase and runs error handlers.
+func (c *Crawler) onError(err error) {
+	var fetchErr *FetchError
+	if !errors.As(err, &fetchErr) {
+		return
+	}
+
+	c.stat.AddFetchError(fetchErr.Phase)
+	for _, handler := range c.onErrorHandler {
+		handler(fetchErr)
+	}
 }
 
 func (c *Crawler) onFetched(r *http.Request, resp *Response) {
@@ -218,14 +688,43 @@ func (c *Crawler) shouldBeProcessed(rawURL string, url *url.URL) error {
 	if _, ok := c.uniqCrawledLinks[md5s]; ok {
 		return fmt.Errorf("url '%s': %w", rawURL, ErrAlreadyCrawled)
 	}
+	if err := c.budget.admit(strings.ToLower(url.Host)); err != nil {
+		return fmt.Errorf("url '%s': %w", rawURL, err)
+	}
 	c.uniqCrawledLinks[md5s] = struct{}{}
 	c.stat.AddUniqDiscovered()
 
-	// others checks
-
 	return nil
 }
 
+// skipReason classifies error of queuing URL.
+func skipReason(err error) SkipReason {
+	switch {
+	case errors.Is(err, ErrAlreadyCrawled):
+		return SkipAlreadyCrawled
+	case errors.Is(err, ErrNotAllowedDomain):
+		return SkipNotAllowedDomain
+	case errors.Is(err, ErrBudgetExceeded):
+		return SkipBudget
+	case errors.Is(err, ErrCrawlerStopped):
+		return SkipStopped
+	}
+
+	return SkipEmptyURL
+}
+
+func (c *Crawler) setDepth(rawURL string, depth int) {
+	c.linksMux.Lock()
+	defer c.linksMux.Unlock()
+	c.depths[rawURL] = depth
+}
+
+func (c *Crawler) depthOf(rawURL string) int {
+	c.linksMux.Lock()
+	defer c.linksMux.Unlock()
+	return c.depths[rawURL]
+}
+
 func (c *Crawler) domainAllowed(domain string) bool {
 	for _, allowedDomain := range c.cfg.allowedDomains {
 		if domain == allowedDomain {
@@ -235,3 +734,29 @@ func (c *Crawler) domainAllowed(domain string) bool {
 
 	return false
 }
+
+// redirects returns number of redirects followed to get response.
+func redirects(resp *http.Response) int {
+	n := 0
+	for r := resp.Request.Response; r != nil; r = r.Request.Response {
+		n++
+	}
+
+	return n
+}
+
+func isHTML(contentType string) bool {
+	return strings.Contains(contentType, "text/html")
+}
+
+// wrapTransport returns copy of client with wrapped transport, so client passed with WithClient is not modified.
+func wrapTransport(client *http.Client, wrap func(next http.RoundTripper) http.RoundTripper) *http.Client {
+	wrapped := *client
+	next := client.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	wrapped.Transport = wrap(next)
+
+	return &wrapped
+}
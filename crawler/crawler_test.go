@@ -2,12 +2,28 @@ package crawler
 
 import (
 	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"os"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
+// tempDir creates temporary directory, it's removed with returned func.
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "crawler")
+	assert.NoError(t, err)
+
+	return dir, func() {
+		os.RemoveAll(dir)
+	}
+}
+
 func TestCrawler_shouldBeProcessed(t *testing.T) {
 	crawler := New(
 		WithAllowedDomains("velikodny.com"),
@@ -31,3 +47,182 @@ func TestCrawler_shouldBeProcessed(t *testing.T) {
 		assert.True(t, errors.Is(crawler.shouldBeProcessed("https://velikodny1.com", u), ErrNotAllowedDomain))
 	})
 }
+
+type recordsOutput struct {
+	mux     sync.Mutex
+	records map[string]*PageRecord
+}
+
+func (o *recordsOutput) WritePage(record *PageRecord) error {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	o.records[record.URL] = record
+	return nil
+}
+
+func (o *recordsOutput) WriteLink(*Link) error {
+	return nil
+}
+
+func (o *recordsOutput) Close() error {
+	return nil
+}
+
+// signalOutput sends URL of every written page record to written.
+type signalOutput struct {
+	*recordsOutput
+	written chan string
+}
+
+func (o *signalOutput) WritePage(record *PageRecord) error {
+	err := o.recordsOutput.WritePage(record)
+	o.written <- record.URL
+	return err
+}
+
+func newTestSite() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><head><title>Root &amp; home</title><meta name="description" content="root page"></head>
+			<body><a href="/a">a</a><a href="/missing">missing</a></body></html>`)
+	})
+	mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<a href="/">root</a><img src="/b.png">`)
+	})
+	mux.HandleFunc("/b.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		fmt.Fprint(w, "png")
+	})
+	mux.HandleFunc("/missing", http.NotFound)
+	return httptest.NewServer(mux)
+}
+
+func TestCrawler_WithOutput(t *testing.T) {
+	server := newTestSite()
+	defer server.Close()
+
+	output := &recordsOutput{records: make(map[string]*PageRecord)}
+	crawler := New(
+		WithConcurrency(2),
+		WithOutput(output),
+	)
+	crawler.OnFetched(func(request *http.Request, response *Response) {
+		for _, link := range crawler.Links(request, response) {
+			crawler.VisitLink(link)
+		}
+	})
+
+	assert.NoError(t, crawler.Run(server.URL+"/"))
+	crawler.Wait()
+
+	assert.Equal(t, 4, len(output.records))
+
+	root := output.records[server.URL+"/"]
+	assert.Equal(t, 0, root.Depth)
+	assert.Equal(t, http.StatusOK, root.StatusCode)
+	assert.Equal(t, []string{server.URL + "/a", server.URL + "/missing"}, root.Outlinks)
+	assert.Equal(t, "Root & home", root.Title)
+	assert.Equal(t, "root page", root.Description)
+
+	assert.Equal(t, 1, output.records[server.URL+"/a"].Depth)
+	assert.Equal(t, 2, output.records[server.URL+"/b.png"].Depth)
+	assert.Equal(t, 3, output.records[server.URL+"/b.png"].ContentLength)
+
+	missing := output.records[server.URL+"/missing"]
+	assert.Equal(t, http.StatusNotFound, missing.StatusCode)
+	assert.NotEmpty(t, missing.Error)
+}
+
+// linksOutput records discovered links and doesn't use page info.
+type linksOutput struct {
+	*recordsOutput
+	links []*Link
+}
+
+func (o *linksOutput) WriteLink(link *Link) error {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	o.links = append(o.links, link)
+	return nil
+}
+
+func (o *linksOutput) usesPageInfo() bool {
+	return false
+}
+
+func TestCrawler_WithOutputParsesOnce(t *testing.T) {
+	server := newTestSite()
+	defer server.Close()
+
+	for _, pageInfo := range []bool{false, true} {
+		output := &linksOutput{recordsOutput: &recordsOutput{records: make(map[string]*PageRecord)}}
+		outputs := []Output{output}
+		if pageInfo {
+			outputs = append(outputs, &recordsOutput{records: make(map[string]*PageRecord)})
+		}
+		var links []*Link
+		crawler := New(WithOutput(outputs...))
+		crawler.OnFetched(func(request *http.Request, response *Response) {
+			links = crawler.Links(request, response)
+		})
+
+		assert.NoError(t, crawler.Visit(server.URL+"/"))
+		crawler.Wait()
+
+		// outputs get links extracted for handler
+		assert.Equal(t, 2, len(links))
+		assert.Equal(t, links, output.links)
+		for i := range links {
+			assert.Same(t, links[i], output.links[i])
+		}
+
+		// page head is parsed only if some output uses page info
+		root := output.records[server.URL+"/"]
+		if pageInfo {
+			assert.Equal(t, "Root & home", root.Title)
+		} else {
+			assert.Empty(t, root.Title)
+			assert.Empty(t, root.Description)
+		}
+	}
+}
+
+func TestCrawler_PauseResume(t *testing.T) {
+	server := newTestSite()
+	defer server.Close()
+
+	output := &signalOutput{
+		recordsOutput: &recordsOutput{records: make(map[string]*PageRecord)},
+		written:       make(chan string, 10),
+	}
+	crawler := New(
+		WithConcurrency(2),
+		WithOutput(output),
+	)
+	crawler.OnFetched(func(request *http.Request, response *Response) {
+		links := crawler.Links(request, response)
+		// pause after root page, its links are queued
+		if response.Depth == 0 {
+			crawler.Pause()
+		}
+		for _, link := range links {
+			crawler.VisitLink(link)
+		}
+	})
+
+	assert.NoError(t, crawler.Run(server.URL+"/"))
+	assert.Equal(t, server.URL+"/", <-output.written)
+
+	assert.True(t, crawler.Paused())
+	output.mux.Lock()
+	assert.Equal(t, 1, len(output.records))
+	output.mux.Unlock()
+
+	crawler.Resume()
+	crawler.Wait()
+
+	assert.False(t, crawler.Paused())
+	assert.Equal(t, 4, len(output.records))
+}
@@ -44,6 +44,8 @@ func (extractor *extractor) extractLinks(source *url.URL, response *Response) []
 
 	var currentToken html.Token
 	var linkAttrs map[string]string
+	// anchor collects text of currently open "a" tag
+	var anchor *Link
 
 	for {
 		tt := z.Next()
@@ -51,7 +53,19 @@ func (extractor *extractor) extractLinks(source *url.URL, response *Response) []
 		switch {
 		case tt == html.ErrorToken:
 			// End of the document, we're done
+			if anchor != nil {
+				anchor.Text = strings.Join(strings.Fields(anchor.Text), " ")
+			}
 			return results
+		case tt == html.TextToken:
+			if anchor != nil {
+				anchor.Text += string(z.Text())
+			}
+		case tt == html.EndTagToken:
+			if anchor != nil && z.Token().Data == "a" {
+				anchor.Text = strings.Join(strings.Fields(anchor.Text), " ")
+				anchor = nil
+			}
 		case tt == html.StartTagToken, tt == html.SelfClosingTagToken:
 			currentToken = z.Token()
 
@@ -75,6 +89,10 @@ func (extractor *extractor) extractLinks(source *url.URL, response *Response) []
 				if len(ref) > 0 {
 					sourceLink, _ := NewLink(source.String())
 					resourceLink := NewHrefLink(sourceLink, ref)
+					resourceLink.Tag = currentToken.Data
+					if currentToken.Data == "link" {
+						resourceLink.Rel = attrs["rel"]
+					}
 					results = append(results, resourceLink)
 				}
 			}
@@ -83,8 +101,12 @@ func (extractor *extractor) extractLinks(source *url.URL, response *Response) []
 			if currentToken.Data == "a" && len(linkAttrs["href"]) > 0 {
 				sourceLink, _ := NewLink(source.String())
 				link := NewHrefLink(sourceLink, linkAttrs["href"])
+				link.Tag = currentToken.Data
 
 				results = append(results, link)
+				if tt == html.StartTagToken {
+					anchor = link
+				}
 			}
 		}
 	}
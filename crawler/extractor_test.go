@@ -30,3 +30,25 @@ func TestExtractor_ExtractLinks(t *testing.T) {
 	assert.Equal(t, links[2].Url.String(), "https://velikodny.com/3.jpg")
 	assert.Equal(t, links[3].Url.String(), "https://velikodny.com/4.png")
 }
+
+func TestExtractor_ExtractLinksTagText(t *testing.T) {
+	extractor := NewExtractor("velikodny.com")
+
+	source, _ := url.Parse("https://velikodny.com")
+	links := extractor.ExtractLinks(source, &Response{
+		Body: []byte(`
+			<a href="https://velikodny.com/1">test <b>link</b>
+				1</a>
+			<a href="https://velikodny.com/2"/>
+			<img src="https://velikodny.com/3.jpg" alt="image"/>
+		`),
+	})
+
+	assert.Equal(t, 3, len(links))
+	assert.Equal(t, "a", links[0].Tag)
+	assert.Equal(t, "test link 1", links[0].Text)
+	assert.Equal(t, "a", links[1].Tag)
+	assert.Equal(t, "", links[1].Text)
+	assert.Equal(t, "img", links[2].Tag)
+	assert.Equal(t, "", links[2].Text)
+}
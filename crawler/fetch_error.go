@@ -0,0 +1,108 @@
+package crawler
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+var (
+	ErrHTTPStatus = errors.New("unexpected HTTP status")
+	ErrTooLarge   = errors.New("response body too large")
+)
+
+// FetchPhase classifies fetch failure by the phase it happened in.
+type FetchPhase string
+
+const (
+	PhaseDNS        FetchPhase = "dns"
+	PhaseConnect    FetchPhase = "connect"
+	PhaseTLS        FetchPhase = "tls"
+	PhaseTimeout    FetchPhase = "timeout"
+	PhaseProxy      FetchPhase = "proxy"
+	PhaseLogin      FetchPhase = "login"
+	PhaseHTTPStatus FetchPhase = "http_status"
+	PhaseBodyRead   FetchPhase = "body_read"
+	PhaseTooLarge   FetchPhase = "too_large"
+	// PhaseRequest is any other failure to send request.
+	PhaseRequest FetchPhase = "request"
+)
+
+// FetchError describes failed fetch of URL.
+type FetchError struct {
+	URL   string
+	Phase FetchPhase
+	// StatusCode is set if response was received.
+	StatusCode int
+	Err        error
+}
+
+func (e *FetchError) Error() string {
+	if e.Phase == PhaseHTTPStatus {
+		return fmt.Sprintf("url: %s, status: %d", e.URL, e.StatusCode)
+	}
+
+	return fmt.Sprintf("url: %s, %s: %s", e.URL, e.Phase, e.Err)
+}
+
+func (e *FetchError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches target FetchError by phase and status code, if they are set,
+// e.g. errors.Is(err, &FetchError{Phase: PhaseTimeout}).
+func (e *FetchError) Is(target error) bool {
+	t, ok := target.(*FetchError)
+	if !ok {
+		return false
+	}
+
+	return (t.Phase == "" || t.Phase == e.Phase) && (t.StatusCode == 0 || t.StatusCode == e.StatusCode)
+}
+
+// requestPhase classifies error of sending request.
+func requestPhase(err error) FetchPhase {
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	var recordErr tls.RecordHeaderError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certErr x509.CertificateInvalidError
+
+	switch {
+	case errors.Is(err, ErrProxy):
+		return PhaseProxy
+	case errors.As(err, &dnsErr):
+		return PhaseDNS
+	case isTimeout(err):
+		return PhaseTimeout
+	case errors.As(err, &recordErr), errors.As(err, &authorityErr), errors.As(err, &hostnameErr), errors.As(err, &certErr),
+		strings.Contains(err.Error(), "tls: "):
+		return PhaseTLS
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		return PhaseConnect
+	}
+
+	return PhaseRequest
+}
+
+// bodyPhase classifies error of reading response body.
+func bodyPhase(err error) FetchPhase {
+	switch {
+	case errors.Is(err, ErrTooLarge):
+		return PhaseTooLarge
+	case isTimeout(err):
+		return PhaseTimeout
+	}
+
+	return PhaseBodyRead
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
@@ -0,0 +1,98 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestPhase(t *testing.T) {
+	wrap := func(err error) error {
+		return &url.Error{Op: "Get", URL: "http://velikodny.com", Err: err}
+	}
+
+	assert.Equal(t, PhaseDNS, requestPhase(wrap(&net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "velikodny.com"}})))
+	assert.Equal(t, PhaseTimeout, requestPhase(wrap(context.DeadlineExceeded)))
+	assert.Equal(t, PhaseConnect, requestPhase(wrap(&net.OpError{Op: "dial", Err: errors.New("connection refused")})))
+	assert.Equal(t, PhaseTLS, requestPhase(wrap(errors.New("tls: handshake failure"))))
+	assert.Equal(t, PhaseProxy, requestPhase(wrap(&ProxyError{Err: ErrNoHealthyProxy})))
+	assert.Equal(t, PhaseRequest, requestPhase(wrap(errors.New("unsupported protocol scheme"))))
+}
+
+func TestFetchError_Is(t *testing.T) {
+	err := fmt.Errorf("fetch: %w", &FetchError{URL: "http://velikodny.com", Phase: PhaseHTTPStatus, StatusCode: 404, Err: ErrHTTPStatus})
+
+	assert.True(t, errors.Is(err, &FetchError{Phase: PhaseHTTPStatus}))
+	assert.True(t, errors.Is(err, &FetchError{StatusCode: 404}))
+	assert.False(t, errors.Is(err, &FetchError{Phase: PhaseHTTPStatus, StatusCode: 500}))
+	assert.False(t, errors.Is(err, &FetchError{Phase: PhaseTimeout}))
+	assert.True(t, errors.Is(err, ErrHTTPStatus))
+
+	var fetchErr *FetchError
+	assert.True(t, errors.As(err, &fetchErr))
+	assert.Equal(t, "url: http://velikodny.com, status: 404", fetchErr.Error())
+}
+
+func TestCrawler_OnError(t *testing.T) {
+	server := newTestSite()
+	defer server.Close()
+	tlsServer := httptest.NewTLSServer(http.NotFoundHandler())
+	defer tlsServer.Close()
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	var mux sync.Mutex
+	errs := make(map[string]*FetchError)
+	output := &recordsOutput{records: make(map[string]*PageRecord)}
+	crawler := New(WithOutput(output))
+	crawler.OnError(func(err *FetchError) {
+		mux.Lock()
+		defer mux.Unlock()
+		errs[err.URL] = err
+	})
+
+	assert.NoError(t, crawler.Visit(server.URL+"/missing"))
+	assert.NoError(t, crawler.Visit(tlsServer.URL+"/"))
+	assert.NoError(t, crawler.Visit(dead.URL+"/"))
+	crawler.Wait()
+
+	assert.Equal(t, &FetchError{URL: server.URL + "/missing", Phase: PhaseHTTPStatus, StatusCode: 404, Err: ErrHTTPStatus}, errs[server.URL+"/missing"])
+	assert.Equal(t, PhaseTLS, errs[tlsServer.URL+"/"].Phase)
+	assert.Equal(t, PhaseConnect, errs[dead.URL+"/"].Phase)
+
+	assert.Equal(t, PhaseHTTPStatus, output.records[server.URL+"/missing"].ErrorClass)
+	assert.Equal(t, map[FetchPhase]int64{PhaseHTTPStatus: 1, PhaseTLS: 1, PhaseConnect: 1}, crawler.Stat().FetchErrors())
+}
+
+func TestCrawler_WithMaxBodySize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/chunked" {
+			// no Content-Length, body is read until limit
+			w.(http.Flusher).Flush()
+		}
+		fmt.Fprint(w, strings.Repeat("a", 100))
+	}))
+	defer server.Close()
+
+	output := &recordsOutput{records: make(map[string]*PageRecord)}
+	crawler := New(WithOutput(output), WithMaxBodySize(10))
+	assert.NoError(t, crawler.Visit(server.URL+"/"))
+	assert.NoError(t, crawler.Visit(server.URL+"/chunked"))
+	crawler.Wait()
+
+	for _, path := range []string{"/", "/chunked"} {
+		record := output.records[server.URL+path]
+		assert.Equal(t, PhaseTooLarge, record.ErrorClass, path)
+		assert.Equal(t, http.StatusOK, record.StatusCode, path)
+	}
+}
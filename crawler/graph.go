@@ -0,0 +1,272 @@
+package crawler
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// GraphNode is a page of site link graph.
+type GraphNode struct {
+	URL string `json:"url"`
+	// Status holds HTTP status code of fetched page, 0 if page was not fetched.
+	Status int `json:"status"`
+}
+
+// GraphEdge is a link from one page to another.
+type GraphEdge struct {
+	From string `json:"-"`
+	To   string `json:"url"`
+	Text string `json:"text,omitempty"`
+	Tag  string `json:"tag"`
+}
+
+// Graph is in-memory directed graph of page-to-page links built while crawling.
+// Graph implements Output, so it is populated by passing it to WithOutput.
+// Links to resources like images and scripts are not page-to-page edges and are skipped.
+type Graph struct {
+	mux   sync.Mutex
+	nodes map[string]*GraphNode
+	// edges holds outgoing edges of page, one edge per linked page
+	edges map[string][]*GraphEdge
+	// linked holds "from to" pairs of edges already added
+	linked map[[2]string]struct{}
+}
+
+// NewGraph creates empty Graph.
+func NewGraph() *Graph {
+	return &Graph{
+		nodes:  make(map[string]*GraphNode),
+		edges:  make(map[string][]*GraphEdge),
+		linked: make(map[[2]string]struct{}),
+	}
+}
+
+// WritePage adds fetched page to graph. Resources are added only if some page links to them.
+func (g *Graph) WritePage(record *PageRecord) error {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+
+	if _, ok := g.nodes[record.URL]; !ok && !isHTML(record.ContentType) {
+		return nil
+	}
+
+	g.node(record.URL).Status = record.StatusCode
+	return nil
+}
+
+// WriteLink adds edge from page link discovered on to linked page.
+func (g *Graph) WriteLink(link *Link) error {
+	if link.IsRejected() || link.Tag != "a" {
+		return nil
+	}
+
+	g.AddEdge(&GraphEdge{
+		From: link.Source,
+		To:   link.Url.String(),
+		Text: link.Text,
+		Tag:  link.Tag,
+	})
+	return nil
+}
+
+func (g *Graph) usesPageInfo() bool {
+	return false
+}
+
+// Close does nothing, graph is exported with Write methods when crawling is finished.
+func (g *Graph) Close() error {
+	return nil
+}
+
+// AddEdge adds edge to graph, adding missing nodes. Only the first edge between two pages is kept.
+func (g *Graph) AddEdge(edge *GraphEdge) {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+
+	g.node(edge.From)
+	g.node(edge.To)
+
+	key := [2]string{edge.From, edge.To}
+	if _, ok := g.linked[key]; ok {
+		return
+	}
+	g.linked[key] = struct{}{}
+	g.edges[edge.From] = append(g.edges[edge.From], edge)
+}
+
+// Nodes returns graph nodes sorted by URL.
+func (g *Graph) Nodes() []*GraphNode {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+
+	nodes := make([]*GraphNode, 0, len(g.nodes))
+	for _, node := range g.nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].URL < nodes[j].URL
+	})
+
+	return nodes
+}
+
+// Edges returns outgoing edges of page in discovery order.
+func (g *Graph) Edges(url string) []*GraphEdge {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+
+	return g.edges[url]
+}
+
+// WriteDOT exports graph in Graphviz DOT format.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	ew := &errWriter{w: w}
+
+	fmt.Fprintln(ew, "digraph crawl {")
+	for _, node := range g.Nodes() {
+		fmt.Fprintf(ew, "  %s [label=%s, status=%d];\n", dotQuote(node.URL), dotQuote(node.URL+"\n"+strconv.Itoa(node.Status)), node.Status)
+	}
+	for _, node := range g.Nodes() {
+		for _, edge := range g.Edges(node.URL) {
+			fmt.Fprintf(ew, "  %s -> %s [label=%s, tag=%s];\n", dotQuote(edge.From), dotQuote(edge.To), dotQuote(edge.Text), dotQuote(edge.Tag))
+		}
+	}
+	fmt.Fprintln(ew, "}")
+
+	return ew.err
+}
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// WriteGraphML exports graph in GraphML format.
+func (g *Graph) WriteGraphML(w io.Writer) error {
+	doc := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "status", For: "node", Name: "status", Type: "int"},
+			{ID: "text", For: "edge", Name: "text", Type: "string"},
+			{ID: "tag", For: "edge", Name: "tag", Type: "string"},
+		},
+		Graph: graphMLGraph{EdgeDefault: "directed"},
+	}
+
+	for _, node := range g.Nodes() {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID:   node.URL,
+			Data: []graphMLData{{Key: "status", Value: strconv.Itoa(node.Status)}},
+		})
+		for _, edge := range g.Edges(node.URL) {
+			doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+				Source: edge.From,
+				Target: edge.To,
+				Data:   []graphMLData{{Key: "text", Value: edge.Text}, {Key: "tag", Value: edge.Tag}},
+			})
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+type jsonGraphNode struct {
+	*GraphNode
+	Outlinks []*GraphEdge `json:"outlinks"`
+}
+
+// WriteJSON exports graph as JSON adjacency list: every node with its outgoing edges.
+func (g *Graph) WriteJSON(w io.Writer) error {
+	nodes := make([]jsonGraphNode, 0)
+	for _, node := range g.Nodes() {
+		edges := g.Edges(node.URL)
+		if edges == nil {
+			edges = []*GraphEdge{}
+		}
+		nodes = append(nodes, jsonGraphNode{GraphNode: node, Outlinks: edges})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Nodes []jsonGraphNode `json:"nodes"`
+	}{nodes})
+}
+
+// node returns graph node, adding it if missing. Must be called with mux locked.
+func (g *Graph) node(url string) *GraphNode {
+	node, ok := g.nodes[url]
+	if !ok {
+		node = &GraphNode{URL: url}
+		g.nodes[url] = node
+	}
+
+	return node
+}
+
+func dotQuote(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", "").Replace(s)
+	return `"` + s + `"`
+}
+
+// errWriter remembers the first write error, so formatted output can be checked once.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) Write(p []byte) (int, error) {
+	if ew.err != nil {
+		return 0, ew.err
+	}
+
+	n, err := ew.w.Write(p)
+	ew.err = err
+	return n, err
+}
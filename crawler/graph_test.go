@@ -0,0 +1,94 @@
+package crawler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestGraph() *Graph {
+	g := NewGraph()
+
+	extractor := NewExtractor()
+	source, _ := url.Parse("https://velikodny.com/")
+	links := extractor.ExtractLinks(source, &Response{Body: []byte(`
+		<a href="/a">Page "A"</a>
+		<a href="/a">A again</a>
+		<a href="/b">B</a>
+		<img src="/1.png">
+		<a href="mailto:me@velikodny.com">mail</a>
+	`)})
+
+	g.WritePage(&PageRecord{URL: "https://velikodny.com/", StatusCode: 200, ContentType: "text/html"})
+	g.WritePage(&PageRecord{URL: "https://velikodny.com/1.png", StatusCode: 200, ContentType: "image/png"})
+	for _, link := range links {
+		g.WriteLink(link)
+	}
+	g.WritePage(&PageRecord{URL: "https://velikodny.com/b", StatusCode: 404})
+
+	return g
+}
+
+func TestGraph(t *testing.T) {
+	g := newTestGraph()
+
+	nodes := g.Nodes()
+	assert.Equal(t, 3, len(nodes))
+	assert.Equal(t, &GraphNode{URL: "https://velikodny.com/", Status: 200}, nodes[0])
+	assert.Equal(t, &GraphNode{URL: "https://velikodny.com/a", Status: 0}, nodes[1])
+	assert.Equal(t, &GraphNode{URL: "https://velikodny.com/b", Status: 404}, nodes[2])
+
+	edges := g.Edges("https://velikodny.com/")
+	assert.Equal(t, 2, len(edges))
+	assert.Equal(t, `Page "A"`, edges[0].Text)
+	assert.Equal(t, "a", edges[0].Tag)
+}
+
+func TestGraph_WriteDOT(t *testing.T) {
+	var b bytes.Buffer
+	assert.NoError(t, newTestGraph().WriteDOT(&b))
+
+	assert.Equal(t, `digraph crawl {
+  "https://velikodny.com/" [label="https://velikodny.com/\n200", status=200];
+  "https://velikodny.com/a" [label="https://velikodny.com/a\n0", status=0];
+  "https://velikodny.com/b" [label="https://velikodny.com/b\n404", status=404];
+  "https://velikodny.com/" -> "https://velikodny.com/a" [label="Page \"A\"", tag="a"];
+  "https://velikodny.com/" -> "https://velikodny.com/b" [label="B", tag="a"];
+}
+`, b.String())
+}
+
+func TestGraph_WriteGraphML(t *testing.T) {
+	var b bytes.Buffer
+	assert.NoError(t, newTestGraph().WriteGraphML(&b))
+
+	assert.Contains(t, b.String(), `<graph edgedefault="directed">`)
+	assert.Contains(t, b.String(), `<node id="https://velikodny.com/b">`)
+	assert.Contains(t, b.String(), `<edge source="https://velikodny.com/" target="https://velikodny.com/a">`)
+	assert.Contains(t, b.String(), `<data key="text">Page &#34;A&#34;</data>`)
+}
+
+func TestGraph_WriteJSON(t *testing.T) {
+	var b bytes.Buffer
+	assert.NoError(t, newTestGraph().WriteJSON(&b))
+
+	var doc struct {
+		Nodes []struct {
+			URL      string
+			Status   int
+			Outlinks []struct {
+				URL  string
+				Text string
+				Tag  string
+			}
+		}
+	}
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &doc))
+	assert.Equal(t, 3, len(doc.Nodes))
+	assert.Equal(t, 2, len(doc.Nodes[0].Outlinks))
+	assert.Equal(t, "https://velikodny.com/b", doc.Nodes[0].Outlinks[1].URL)
+	assert.Equal(t, 0, len(doc.Nodes[1].Outlinks))
+}
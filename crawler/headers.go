@@ -0,0 +1,76 @@
+package crawler
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+)
+
+// requestHeaders are added to requests by transport. Host headers are added to requests
+// to that host only, so credentials are never sent to other hosts, e.g. on redirects.
+type requestHeaders struct {
+	global http.Header
+	// hosts holds headers by lower case host, with port if it's set
+	hosts map[string]http.Header
+}
+
+func newRequestHeaders() *requestHeaders {
+	return &requestHeaders{
+		global: make(http.Header),
+		hosts:  make(map[string]http.Header),
+	}
+}
+
+// set sets header for all hosts if host is empty.
+func (h *requestHeaders) set(host, key, value string) {
+	if host == "" {
+		h.global.Set(key, value)
+		return
+	}
+
+	host = strings.ToLower(host)
+	if h.hosts[host] == nil {
+		h.hosts[host] = make(http.Header)
+	}
+	h.hosts[host].Set(key, value)
+}
+
+func (h *requestHeaders) empty() bool {
+	return len(h.global) == 0 && len(h.hosts) == 0
+}
+
+// Transport wraps next transport to add headers.
+func (h *requestHeaders) Transport(next http.RoundTripper) http.RoundTripper {
+	return &headersTransport{headers: h, next: next}
+}
+
+type headersTransport struct {
+	headers *requestHeaders
+	next    http.RoundTripper
+}
+
+func (t *headersTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrip must not modify request
+	req = req.Clone(req.Context())
+
+	copyHeader(req.Header, t.headers.global)
+
+	// headers for host with port take precedence over headers for host
+	hostname := strings.ToLower(req.URL.Hostname())
+	copyHeader(req.Header, t.headers.hosts[hostname])
+	if host := strings.ToLower(req.URL.Host); host != hostname {
+		copyHeader(req.Header, t.headers.hosts[host])
+	}
+
+	return t.next.RoundTrip(req)
+}
+
+func copyHeader(dst, src http.Header) {
+	for key, values := range src {
+		dst[key] = values
+	}
+}
+
+func basicAuth(username, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
@@ -0,0 +1,58 @@
+package crawler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCrawler_WithHeaders(t *testing.T) {
+	var mux sync.Mutex
+	received := make(map[string]http.Header)
+	record := func(w http.ResponseWriter, r *http.Request) {
+		mux.Lock()
+		defer mux.Unlock()
+		received[r.URL.Path] = r.Header.Clone()
+	}
+
+	other := httptest.NewServer(http.HandlerFunc(record))
+	defer other.Close()
+	otherURL, _ := url.Parse(other.URL)
+	otherURL.Host = "localhost:" + otherURL.Port()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		record(w, r)
+		http.Redirect(w, r, otherURL.String()+"/other", http.StatusFound)
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	crawler := New(WithConcurrency(1),
+		WithUserAgent("crawlerbot/1.0"),
+		WithAcceptLanguage("en-US,en;q=0.9"),
+		WithHeader("X-Crawl", "1"),
+		WithHostHeader(serverURL.Hostname(), "X-Env", "staging"),
+		WithBasicAuth(serverURL.Host, "admin", "secret"),
+		WithBearerToken("localhost", "token"),
+	)
+	assert.NoError(t, crawler.Run(server.URL+"/"))
+	crawler.Wait()
+
+	header := received["/"]
+	assert.Equal(t, "crawlerbot/1.0", header.Get("User-Agent"))
+	assert.Equal(t, "en-US,en;q=0.9", header.Get("Accept-Language"))
+	assert.Equal(t, "1", header.Get("X-Crawl"))
+	assert.Equal(t, "staging", header.Get("X-Env"))
+	assert.Equal(t, "Basic YWRtaW46c2VjcmV0", header.Get("Authorization"))
+
+	// host scoped headers are not sent to another host on redirect
+	header = received["/other"]
+	assert.Equal(t, "crawlerbot/1.0", header.Get("User-Agent"))
+	assert.Equal(t, "1", header.Get("X-Crawl"))
+	assert.Equal(t, "", header.Get("X-Env"))
+	assert.Equal(t, "Bearer token", header.Get("Authorization"))
+}
@@ -0,0 +1,130 @@
+package crawler
+
+import (
+	"sync"
+	"time"
+)
+
+// latencyBuckets are upper bounds of response time histogram buckets.
+var latencyBuckets = []time.Duration{
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// HistogramBucket is count of observations less than or equal to bucket bound.
+type HistogramBucket struct {
+	LE    time.Duration `json:"le"`
+	Count int64         `json:"count"`
+}
+
+// HistogramSnapshot is state of duration histogram. Buckets are cumulative,
+// observations greater than the last bound are counted in Count only.
+type HistogramSnapshot struct {
+	Buckets []HistogramBucket `json:"buckets"`
+	Count   int64             `json:"count"`
+	Sum     time.Duration     `json:"sum"`
+	Max     time.Duration     `json:"max"`
+	P50     time.Duration     `json:"p50"`
+	P90     time.Duration     `json:"p90"`
+	P95     time.Duration     `json:"p95"`
+	P99     time.Duration     `json:"p99"`
+}
+
+// Mean returns average observation.
+func (h HistogramSnapshot) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+
+	return h.Sum / time.Duration(h.Count)
+}
+
+// Quantile estimates q-quantile, 0 <= q <= 1, by linear interpolation within bucket it falls in.
+// Quantiles above the last bound are estimated as the max observation.
+func (h HistogramSnapshot) Quantile(q float64) time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+
+	rank := q * float64(h.Count)
+	var lower time.Duration
+	var lowerCount int64
+	for _, bucket := range h.Buckets {
+		if float64(bucket.Count) >= rank && bucket.Count > lowerCount {
+			d := lower + time.Duration(float64(bucket.LE-lower)*(rank-float64(lowerCount))/float64(bucket.Count-lowerCount))
+			if d > h.Max {
+				return h.Max
+			}
+			return d
+		}
+		lower, lowerCount = bucket.LE, bucket.Count
+	}
+
+	return h.Max
+}
+
+// histogram counts durations in buckets.
+type histogram struct {
+	mux    sync.Mutex
+	bounds []time.Duration
+	// counts of observations per bucket, not cumulative
+	counts []int64
+	count  int64
+	sum    time.Duration
+	max    time.Duration
+}
+
+func newHistogram(bounds []time.Duration) *histogram {
+	return &histogram{
+		bounds: bounds,
+		counts: make([]int64, len(bounds)),
+	}
+}
+
+func (h *histogram) observe(d time.Duration) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	for i, bound := range h.bounds {
+		if d <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += d
+	if d > h.max {
+		h.max = d
+	}
+}
+
+func (h *histogram) snapshot() HistogramSnapshot {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	s := HistogramSnapshot{
+		Buckets: make([]HistogramBucket, len(h.bounds)),
+		Count:   h.count,
+		Sum:     h.sum,
+		Max:     h.max,
+	}
+	var cumulative int64
+	for i, bound := range h.bounds {
+		cumulative += h.counts[i]
+		s.Buckets[i] = HistogramBucket{LE: bound, Count: cumulative}
+	}
+
+	s.P50 = s.Quantile(0.5)
+	s.P90 = s.Quantile(0.9)
+	s.P95 = s.Quantile(0.95)
+	s.P99 = s.Quantile(0.99)
+	return s
+}
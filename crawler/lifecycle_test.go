@@ -0,0 +1,162 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newBlockingSite creates site every page of which links to the next one. Requested paths are sent
+// to started, responses are held until release is closed or request is cancelled.
+func newBlockingSite(started chan<- string, release <-chan struct{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- r.URL.Path
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<a href="%s/next">next</a><a href="/other">other</a>`, r.URL.Path)
+	}))
+}
+
+func visitAll(crawler *Crawler) {
+	crawler.OnFetched(func(request *http.Request, response *Response) {
+		for _, link := range crawler.Links(request, response) {
+			crawler.VisitLink(link)
+		}
+	})
+}
+
+func TestCrawler_DefaultConcurrency(t *testing.T) {
+	server := newTestSite()
+	defer server.Close()
+
+	output := &recordsOutput{records: make(map[string]*PageRecord)}
+	crawler := New(WithOutput(output))
+	visitAll(crawler)
+
+	assert.NoError(t, crawler.Run(server.URL+"/"))
+	crawler.Wait()
+
+	assert.Equal(t, 4, len(output.records))
+}
+
+func TestWithConcurrency_Invalid(t *testing.T) {
+	server := newTestSite()
+	defer server.Close()
+
+	for _, concurrency := range []int{0, -1} {
+		output := &recordsOutput{records: make(map[string]*PageRecord)}
+		crawler := New(WithConcurrency(concurrency), WithOutput(output))
+		visitAll(crawler)
+		assert.Equal(t, DefaultConcurrency, cap(crawler.fetchersLimit), concurrency)
+
+		assert.NoError(t, crawler.Run(server.URL+"/"))
+		crawler.Wait()
+
+		assert.Equal(t, 4, len(output.records), concurrency)
+	}
+}
+
+func TestCrawler_RunAfterWait(t *testing.T) {
+	server := newTestSite()
+	defer server.Close()
+
+	output := &recordsOutput{records: make(map[string]*PageRecord)}
+	crawler := New(WithConcurrency(1), WithOutput(output))
+
+	assert.NoError(t, crawler.Run(server.URL+"/"))
+	crawler.Wait()
+	assert.NoError(t, crawler.Run(server.URL+"/a"))
+	crawler.Wait()
+	assert.NoError(t, crawler.Visit(server.URL+"/b.png"))
+	crawler.Wait()
+
+	assert.Equal(t, 3, len(output.records))
+}
+
+func TestCrawler_Shutdown(t *testing.T) {
+	started, release := make(chan string, 10), make(chan struct{})
+	server := newBlockingSite(started, release)
+	defer server.Close()
+
+	output := &recordsOutput{records: make(map[string]*PageRecord)}
+	crawler := New(WithConcurrency(1), WithOutput(output))
+	visitAll(crawler)
+
+	assert.NoError(t, crawler.Run(server.URL+"/"))
+	<-started
+
+	// root page in flight is fetched once crawler is stopped, its links are refused
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- crawler.Shutdown(ctx)
+	}()
+	<-crawler.stopped
+	close(release)
+
+	assert.NoError(t, <-done)
+	assert.Equal(t, 1, len(output.records))
+	assert.Equal(t, http.StatusOK, output.records[server.URL+"/"].StatusCode)
+
+	assert.True(t, errors.Is(crawler.Visit(server.URL+"/late"), ErrCrawlerStopped))
+	assert.True(t, errors.Is(crawler.Run(server.URL+"/again"), ErrCrawlerStopped))
+	assert.True(t, errors.Is(crawler.Start(), ErrCrawlerStopped))
+
+	// Wait and Shutdown can be called again
+	crawler.Wait()
+	assert.NoError(t, crawler.Shutdown(ctx))
+}
+
+func TestCrawler_ShutdownTimeout(t *testing.T) {
+	started, release := make(chan string, 10), make(chan struct{})
+	server := newBlockingSite(started, release)
+	defer server.Close()
+	defer close(release)
+
+	output := &recordsOutput{records: make(map[string]*PageRecord)}
+	crawler := New(WithConcurrency(2), WithOutput(output), WithClient(&http.Client{}))
+
+	assert.NoError(t, crawler.Run(server.URL+"/"))
+	assert.NoError(t, crawler.Visit(server.URL+"/other"))
+	<-started
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	assert.Equal(t, context.DeadlineExceeded, crawler.Shutdown(ctx))
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+
+	// cancelled fetches are recorded with error
+	assert.Equal(t, 2, len(output.records))
+	for _, record := range output.records {
+		assert.NotEmpty(t, record.Error)
+	}
+}
+
+func TestCrawler_ShutdownPaused(t *testing.T) {
+	server := newTestSite()
+	defer server.Close()
+
+	output := &recordsOutput{records: make(map[string]*PageRecord)}
+	crawler := New(WithConcurrency(1), WithOutput(output))
+	crawler.Pause()
+
+	assert.NoError(t, crawler.Run(server.URL+"/"))
+	assert.NoError(t, crawler.Shutdown(context.Background()))
+	crawler.Wait()
+
+	assert.Equal(t, 0, len(output.records))
+}
@@ -12,13 +12,19 @@ var (
 )
 
 type Link struct {
-	Source    string `bson:"Source"`
-	RawRef    string `bson:"RawRef"`
-	Ref       string `bson:"Ref"`
-	Url       *url.URL
-	Malformed bool   `bson:"Malformed"`
-	SelfLink  bool   `bson:"SelfLink"`
-	Error     string `bson:"Error"`
+	Source    string   `bson:"Source" json:"source"`
+	RawRef    string   `bson:"RawRef" json:"raw_ref"`
+	Ref       string   `bson:"Ref" json:"ref"`
+	Url       *url.URL `json:"-"`
+	Malformed bool     `bson:"Malformed" json:"malformed"`
+	SelfLink  bool     `bson:"SelfLink" json:"self_link"`
+	Error     string   `bson:"Error" json:"error,omitempty"`
+	// Tag holds name of HTML tag link was discovered in, e.g. "a" or "img"
+	Tag string `bson:"Tag" json:"tag,omitempty"`
+	// Text holds anchor text of "a" tag link
+	Text string `bson:"Text" json:"text,omitempty"`
+	// Rel holds rel attribute of "link" tag link, e.g. "stylesheet" or "canonical"
+	Rel string `bson:"Rel" json:"rel,omitempty"`
 }
 
 func NewLink(ref string) (*Link, error) {
@@ -0,0 +1,269 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	ErrLoginFailed = errors.New("login failed")
+)
+
+// Login configures form based authentication performed by Run before crawling.
+// Login page is fetched, its form is submitted with Fields along with hidden inputs
+// of the form, e.g. CSRF tokens, and session cookies are kept in crawler cookie jar.
+// Session is considered expired and crawler logs in again if response is 401 Unauthorized,
+// redirects to login page or contains ExpiredText. Once a page answers 401 right after logging in,
+// 401 is treated as protected page rather than expiry for the rest of the session.
+// Login requests bypass cache, so cached login form never replays stale CSRF token.
+type Login struct {
+	// URL of page with login form.
+	URL string
+	// Fields are form values to submit, e.g. username and password.
+	Fields url.Values
+	// SuccessStatus is status code of form submit response before redirects are followed.
+	// If zero, login succeeds if final response is 200 OK.
+	SuccessStatus int
+	// SuccessURL is URL, absolute or relative to login page, form submit must redirect to.
+	SuccessURL string
+	// SuccessText must be found on page form submit ends on.
+	SuccessText string
+	// ExpiredText on page means session has expired.
+	ExpiredText string
+}
+
+// loginForm is HTML form found on login page.
+type loginForm struct {
+	action *url.URL
+	method string
+	fields url.Values
+	// password form has password input
+	password bool
+}
+
+// authenticate logs in to start session.
+func (c *Crawler) authenticate(ctx context.Context) error {
+	c.loginMux.Lock()
+	defer c.loginMux.Unlock()
+
+	return c.submitLogin(ctx)
+}
+
+// reauthenticate logs in again unless another fetcher already did it since session was started.
+func (c *Crawler) reauthenticate(ctx context.Context, session int) error {
+	c.loginMux.Lock()
+	defer c.loginMux.Unlock()
+
+	if c.loginSession != session {
+		return nil
+	}
+
+	return c.submitLogin(ctx)
+}
+
+// unauthorized checks 401 response to request sent in session means session has expired.
+// Pages answering 401 right after logging in are protected, so 401 doesn't mean expiry
+// for the rest of that session.
+func (c *Crawler) unauthorized(session int, resp *http.Response) bool {
+	if resp.StatusCode != http.StatusUnauthorized {
+		return false
+	}
+
+	c.loginMux.Lock()
+	defer c.loginMux.Unlock()
+
+	return session != c.protectedSession
+}
+
+// protect records page answered 401 to request sent in fresh session.
+func (c *Crawler) protect(session int) {
+	c.loginMux.Lock()
+	defer c.loginMux.Unlock()
+
+	c.protectedSession = session
+}
+
+// session returns number of current login session.
+func (c *Crawler) session() int {
+	c.loginMux.Lock()
+	defer c.loginMux.Unlock()
+
+	return c.loginSession
+}
+
+func (c *Crawler) submitLogin(ctx context.Context) error {
+	loginURL, err := url.Parse(c.login.URL)
+	if err != nil {
+		return err
+	}
+
+	resp, body, err := c.doLogin(ctx, http.MethodGet, loginURL, nil)
+	if err != nil {
+		return fmt.Errorf("login page: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("login page: status %d: %w", resp.StatusCode, ErrLoginFailed)
+	}
+
+	form := parseLoginForm(resp.Request.URL, body)
+	for name, values := range c.login.Fields {
+		form.fields[name] = values
+	}
+
+	submitURL := *form.action
+	var submitBody []byte
+	if form.method == http.MethodGet {
+		submitURL.RawQuery = form.fields.Encode()
+	} else {
+		submitBody = []byte(form.fields.Encode())
+	}
+
+	resp, body, err = c.doLogin(ctx, form.method, &submitURL, submitBody)
+	if err != nil {
+		return fmt.Errorf("login: %w", err)
+	}
+	if err := c.login.check(loginURL, resp, body); err != nil {
+		return err
+	}
+
+	c.loginSession++
+	return nil
+}
+
+func (c *Crawler) doLogin(ctx context.Context, method string, u *url.URL, body []byte) (*http.Response, []byte, error) {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, u.String(), r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	request.Header.Set("Cache-Control", "no-store")
+
+	resp, err := c.client.Do(request)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	return resp, b, err
+}
+
+// check checks form submit response against success conditions.
+func (l *Login) check(loginURL *url.URL, resp *http.Response, body []byte) error {
+	submit := resp
+	for submit.Request.Response != nil {
+		submit = submit.Request.Response
+	}
+
+	if l.SuccessStatus != 0 && submit.StatusCode != l.SuccessStatus {
+		return fmt.Errorf("status %d: %w", submit.StatusCode, ErrLoginFailed)
+	}
+	if l.SuccessStatus == 0 && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d: %w", resp.StatusCode, ErrLoginFailed)
+	}
+
+	if l.SuccessURL != "" {
+		successURL, err := loginURL.Parse(l.SuccessURL)
+		if err != nil {
+			return err
+		}
+		if resp.Request.URL.String() != successURL.String() {
+			return fmt.Errorf("redirected to %s: %w", resp.Request.URL, ErrLoginFailed)
+		}
+	}
+
+	if l.SuccessText != "" && !bytes.Contains(body, []byte(l.SuccessText)) {
+		return fmt.Errorf("success text not found: %w", ErrLoginFailed)
+	}
+
+	return nil
+}
+
+// expired checks response redirects to login page or contains ExpiredText, 401 is checked by crawler.
+func (l *Login) expired(resp *http.Response, body []byte) bool {
+	if l.ExpiredText != "" && bytes.Contains(body, []byte(l.ExpiredText)) {
+		return true
+	}
+
+	loginURL, err := url.Parse(l.URL)
+	if err != nil {
+		return false
+	}
+
+	final := resp.Request.URL
+	return resp.Request.Response != nil && strings.EqualFold(final.Host, loginURL.Host) && final.Path == loginURL.Path
+}
+
+// parseLoginForm finds form with password input on page, or the first form if there is none.
+// Page URL with no fields is returned if page has no form.
+func parseLoginForm(pageURL *url.URL, body []byte) *loginForm {
+	var forms []*loginForm
+	var form *loginForm
+
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+
+		token := z.Token()
+		attrs := extractAttrs(token)
+		switch {
+		case token.Data == "form" && tt == html.StartTagToken:
+			form = &loginForm{action: pageURL, method: http.MethodPost, fields: url.Values{}}
+			if action := attrs["action"]; action != "" {
+				if u, err := pageURL.Parse(action); err == nil {
+					form.action = u
+				}
+			}
+			if strings.EqualFold(attrs["method"], http.MethodGet) {
+				form.method = http.MethodGet
+			}
+			forms = append(forms, form)
+		case token.Data == "form" && tt == html.EndTagToken:
+			form = nil
+		case token.Data == "input" && form != nil:
+			switch strings.ToLower(attrs["type"]) {
+			case "password":
+				form.password = true
+			case "submit", "button", "image", "reset", "file":
+				continue
+			case "checkbox", "radio":
+				if _, checked := attrs["checked"]; !checked {
+					continue
+				}
+			}
+			if name := attrs["name"]; name != "" {
+				form.fields.Add(name, attrs["value"])
+			}
+		}
+	}
+
+	for _, form := range forms {
+		if form.password {
+			return form
+		}
+	}
+	if len(forms) > 0 {
+		return forms[0]
+	}
+
+	return &loginForm{action: pageURL, method: http.MethodPost, fields: url.Values{}}
+}
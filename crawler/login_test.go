@@ -0,0 +1,245 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newLoginSite creates site with pages behind login form, session expires after every expireAfter pages.
+func newLoginSite(expireAfter int) (*httptest.Server, *int) {
+	var mux sync.Mutex
+	logins := 0
+	session := ""
+	served := 0
+
+	handler := http.NewServeMux()
+	handler.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		mux.Lock()
+		defer mux.Unlock()
+
+		if r.Method == http.MethodGet {
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body>
+<form action="/search"><input name="q"></form>
+<form method="post" action="/login">
+<input type="hidden" name="csrf" value="token">
+<input name="user"><input type="password" name="password">
+<input type="checkbox" name="remember" value="1" checked>
+<input type="submit" name="go" value="Sign in">
+</form></body></html>`)
+			return
+		}
+
+		r.ParseForm()
+		if r.PostForm.Get("csrf") != "token" || r.PostForm.Get("user") != "admin" || r.PostForm.Get("password") != "secret" ||
+			r.PostForm.Get("remember") != "1" || r.PostForm.Get("go") != "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		logins++
+		session = fmt.Sprintf("s%d", logins)
+		served = 0
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: session, Path: "/"})
+		http.Redirect(w, r, "/", http.StatusFound)
+	})
+	handler.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		mux.Lock()
+		defer mux.Unlock()
+
+		cookie, err := r.Cookie("session")
+		if err != nil || cookie.Value != session || served == expireAfter {
+			session = ""
+			http.Redirect(w, r, "/login", http.StatusFound)
+			return
+		}
+		served++
+
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body>Welcome, admin<a href="/a">a</a><a href="/b">b</a></body></html>`)
+	})
+
+	return httptest.NewServer(handler), &logins
+}
+
+func TestParseLoginForm(t *testing.T) {
+	page, _ := url.Parse("http://velikodny.com/account/login")
+
+	form := parseLoginForm(page, []byte(`<form action="/search"><input name="q"></form>
+<form method="POST" action="session"><input type="hidden" name="csrf" value="abc"><input type="password" name="password"></form>`))
+	assert.Equal(t, "http://velikodny.com/account/session", form.action.String())
+	assert.Equal(t, http.MethodPost, form.method)
+	assert.Equal(t, url.Values{"csrf": {"abc"}, "password": {""}}, form.fields)
+
+	form = parseLoginForm(page, []byte(`<form method="get"><input name="q" value="x"></form>`))
+	assert.Equal(t, page, form.action)
+	assert.Equal(t, http.MethodGet, form.method)
+	assert.Equal(t, url.Values{"q": {"x"}}, form.fields)
+
+	form = parseLoginForm(page, []byte(`<p>no form</p>`))
+	assert.Equal(t, page, form.action)
+	assert.Equal(t, 0, len(form.fields))
+}
+
+func TestCrawler_WithLogin(t *testing.T) {
+	server, logins := newLoginSite(3)
+	defer server.Close()
+
+	output := &recordsOutput{records: make(map[string]*PageRecord)}
+	crawler := New(WithConcurrency(1), WithOutput(output), WithLogin(&Login{
+		URL:         server.URL + "/login",
+		Fields:      url.Values{"user": {"admin"}, "password": {"secret"}},
+		SuccessURL:  "/",
+		SuccessText: "Welcome",
+	}))
+	crawler.OnFetched(func(request *http.Request, response *Response) {
+		for _, link := range crawler.Links(request, response) {
+			crawler.VisitLink(link)
+		}
+	})
+
+	assert.NoError(t, crawler.Run(server.URL+"/"))
+	crawler.Wait()
+
+	// session expires after login page redirect and 2 crawled pages, so crawler logs in again to fetch the third one
+	assert.Equal(t, 2, *logins)
+	assert.Equal(t, 3, len(output.records))
+	for _, record := range output.records {
+		assert.Equal(t, http.StatusOK, record.StatusCode, record.URL)
+		assert.False(t, strings.HasSuffix(record.FinalURL, "/login"), record.URL)
+	}
+}
+
+func TestCrawler_WithLoginFailed(t *testing.T) {
+	server, logins := newLoginSite(0)
+	defer server.Close()
+
+	crawler := New(WithConcurrency(1), WithLogin(&Login{
+		URL:    server.URL + "/login",
+		Fields: url.Values{"user": {"admin"}, "password": {"wrong"}},
+	}))
+
+	assert.ErrorIs(t, crawler.Run(server.URL+"/"), ErrLoginFailed)
+	assert.Equal(t, 0, *logins)
+
+	crawler = New(WithConcurrency(1), WithLogin(&Login{
+		URL:           server.URL + "/login",
+		Fields:        url.Values{"user": {"admin"}, "password": {"secret"}},
+		SuccessStatus: http.StatusSeeOther,
+	}))
+	assert.ErrorIs(t, crawler.Run(server.URL+"/"), ErrLoginFailed)
+}
+
+// newTokenSite creates site issuing new single use CSRF token on every fetch of cacheable login page.
+// Root page links to pages answering 401 to logged in user as well, session expires after every expireAfter pages.
+func newTokenSite(expireAfter int) (*httptest.Server, *int) {
+	var mux sync.Mutex
+	logins, tokens := 0, 0
+	token, session := "", ""
+	served := 0
+
+	handler := http.NewServeMux()
+	handler.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		mux.Lock()
+		defer mux.Unlock()
+
+		if r.Method == http.MethodGet {
+			tokens++
+			token = fmt.Sprintf("t%d", tokens)
+			w.Header().Set("Content-Type", "text/html")
+			w.Header().Set("Cache-Control", "max-age=60")
+			fmt.Fprintf(w, `<form method="post"><input type="hidden" name="csrf" value="%s"><input type="password" name="password"></form>`, token)
+			return
+		}
+
+		r.ParseForm()
+		if r.PostForm.Get("csrf") != token || r.PostForm.Get("password") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		// token is used once
+		token = ""
+		logins++
+		session = fmt.Sprintf("s%d", logins)
+		served = 0
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: session, Path: "/"})
+		w.WriteHeader(http.StatusOK)
+	})
+	handler.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		mux.Lock()
+		defer mux.Unlock()
+
+		cookie, err := r.Cookie("session")
+		if err != nil || cookie.Value != session || served == expireAfter {
+			session = ""
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		served++
+
+		if strings.HasPrefix(r.URL.Path, "/admin/") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<a href="/admin/1">1</a><a href="/admin/2">2</a><a href="/admin/3">3</a><a href="/page">page</a>`)
+	})
+
+	return httptest.NewServer(handler), &logins
+}
+
+func TestCrawler_WithLoginProtectedPages(t *testing.T) {
+	server, logins := newTokenSite(-1)
+	defer server.Close()
+
+	output := &recordsOutput{records: make(map[string]*PageRecord)}
+	crawler := New(WithConcurrency(1), WithOutput(output), WithLogin(&Login{
+		URL:    server.URL + "/login",
+		Fields: url.Values{"password": {"secret"}},
+	}))
+	visitAll(crawler)
+
+	assert.NoError(t, crawler.Run(server.URL+"/"))
+	crawler.Wait()
+
+	// the first protected page is retried after logging in again, others are not
+	assert.Equal(t, 2, *logins)
+	assert.Equal(t, int64(1), crawler.Stat().Snapshot().Retries)
+	for _, path := range []string{"/admin/1", "/admin/2", "/admin/3"} {
+		assert.Equal(t, http.StatusUnauthorized, output.records[server.URL+path].StatusCode, path)
+	}
+	assert.Equal(t, http.StatusOK, output.records[server.URL+"/page"].StatusCode)
+}
+
+func TestCrawler_WithLoginCache(t *testing.T) {
+	server, logins := newTokenSite(1)
+	defer server.Close()
+
+	dir, remove := tempDir(t)
+	defer remove()
+	cache, err := NewCache(dir)
+	assert.NoError(t, err)
+
+	output := &recordsOutput{records: make(map[string]*PageRecord)}
+	crawler := New(WithConcurrency(1), WithOutput(output), WithCache(cache), WithLogin(&Login{
+		URL:    server.URL + "/login",
+		Fields: url.Values{"password": {"secret"}},
+	}))
+
+	// session expires after root page, login form with fresh token is fetched to log in again
+	assert.NoError(t, crawler.Run(server.URL+"/"))
+	crawler.Wait()
+	assert.NoError(t, crawler.Visit(server.URL+"/page"))
+	crawler.Wait()
+
+	assert.Equal(t, 2, *logins)
+	assert.Equal(t, http.StatusOK, output.records[server.URL+"/page"].StatusCode)
+}
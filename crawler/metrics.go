@@ -0,0 +1,190 @@
+package crawler
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MetricsContentType is content type of Prometheus text exposition format.
+const MetricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+type metricsHandler struct {
+	stat PublicStat
+}
+
+// NewMetricsHandler creates handler exposing crawler statistic in Prometheus text exposition format.
+// Metrics are built from Snapshot, so any Stat implementation passed with WithStatistic is exposed.
+func NewMetricsHandler(stat PublicStat) http.Handler {
+	return &metricsHandler{stat: stat}
+}
+
+func (h *metricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", MetricsContentType)
+	if err := WriteMetrics(w, h.stat.Snapshot()); err != nil {
+		log.Println(fmt.Errorf("write metrics: %w", err))
+	}
+}
+
+// WriteMetrics writes statistic snapshot in Prometheus text exposition format.
+func WriteMetrics(w io.Writer, s StatSnapshot) error {
+	m := &metricsWriter{w: bufio.NewWriter(w)}
+
+	m.metric("crawler_urls_discovered_total", "counter", "URLs discovered, including duplicates.", s.TotalDiscovered)
+	m.metric("crawler_urls_unique_total", "counter", "Unique URLs queued to fetch.", s.UniqDiscovered)
+	m.metric("crawler_fetched_total", "counter", "Resources fetched successfully.", s.TotalFetched)
+	m.metric("crawler_pages_total", "counter", "HTML pages fetched successfully.", s.Pages)
+	m.metric("crawler_bytes_total", "counter", "Bytes of response bodies received.", s.Bytes)
+	m.metric("crawler_redirects_total", "counter", "Redirects followed.", s.Redirects)
+	m.metric("crawler_retries_total", "counter", "Requests retried.", s.Retries)
+	m.metric("crawler_cache_hits_total", "counter", "Responses served from cache.", s.CacheHits)
+	m.metric("crawler_cache_misses_total", "counter", "Responses not found in cache.", s.CacheMisses)
+	m.metric("crawler_proxy_errors_total", "counter", "Fetches failed because of proxy.", s.ProxyErrors)
+	m.metric("crawler_queue_length", "gauge", "URLs waiting for fetcher.", s.Queued)
+	m.metric("crawler_in_flight", "gauge", "Fetches in flight.", s.InFlight)
+	m.metric("crawler_conn_reused_total", "counter", "Requests sent over kept-alive connections.", s.Timing.ConnReused)
+
+	codes := make([]int, 0, len(s.StatusCodes))
+	for code := range s.StatusCodes {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	m.header("crawler_responses_total", "counter", "Responses received by status code.")
+	for _, code := range codes {
+		m.sample("crawler_responses_total", labels("code", strconv.Itoa(code)), strconv.FormatInt(s.StatusCodes[code], 10))
+	}
+
+	m.counters("crawler_responses_by_content_type_total", "Responses received by content type.", "content_type", s.ContentTypes)
+
+	errors := make(map[string]int64, len(s.Errors))
+	for class, n := range s.Errors {
+		errors[string(class)] = n
+	}
+	m.counters("crawler_fetch_errors_total", "Failed fetches by error class.", "class", errors)
+
+	skipped := make(map[string]int64, len(s.Skipped))
+	for reason, n := range s.Skipped {
+		skipped[string(reason)] = n
+	}
+	m.counters("crawler_skipped_total", "URLs not fetched by reason.", "reason", skipped)
+
+	m.header("crawler_response_seconds", "histogram", "Response time by host.")
+	for _, host := range sortedHosts(s.HostLatency) {
+		m.histogram("crawler_response_seconds", "host", host, s.HostLatency[host])
+	}
+
+	m.header("crawler_request_phase_seconds", "histogram", "Duration of request phases: dns, connect, tls, ttfb and transfer.")
+	for _, phase := range []struct {
+		name      string
+		histogram HistogramSnapshot
+	}{
+		{"dns", s.Timing.DNS},
+		{"connect", s.Timing.Connect},
+		{"tls", s.Timing.TLS},
+		{"ttfb", s.Timing.TTFB},
+		{"transfer", s.Timing.Transfer},
+	} {
+		m.histogram("crawler_request_phase_seconds", "phase", phase.name, phase.histogram)
+	}
+
+	return m.flush()
+}
+
+// metricsWriter writes metrics keeping the first write error.
+type metricsWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (m *metricsWriter) header(name, kind, help string) {
+	m.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func (m *metricsWriter) metric(name, kind, help string, value int64) {
+	m.header(name, kind, help)
+	m.sample(name, "", strconv.FormatInt(value, 10))
+}
+
+// counters writes counter with label per key of values.
+func (m *metricsWriter) counters(name, help, label string, values map[string]int64) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	m.header(name, "counter", help)
+	for _, key := range keys {
+		m.sample(name, labels(label, key), strconv.FormatInt(values[key], 10))
+	}
+}
+
+func (m *metricsWriter) sample(name, labels, value string) {
+	m.printf("%s%s %s\n", name, labels, value)
+}
+
+func (m *metricsWriter) histogram(name, label, value string, h HistogramSnapshot) {
+	for _, bucket := range h.Buckets {
+		le := strconv.FormatFloat(bucket.LE.Seconds(), 'g', -1, 64)
+		m.sample(name+"_bucket", labels(label, value, "le", le), strconv.FormatInt(bucket.Count, 10))
+	}
+	m.sample(name+"_bucket", labels(label, value, "le", "+Inf"), strconv.FormatInt(h.Count, 10))
+	m.sample(name+"_sum", labels(label, value), strconv.FormatFloat(h.Sum.Seconds(), 'g', -1, 64))
+	m.sample(name+"_count", labels(label, value), strconv.FormatInt(h.Count, 10))
+}
+
+func (m *metricsWriter) printf(format string, args ...interface{}) {
+	if m.err != nil {
+		return
+	}
+	_, m.err = fmt.Fprintf(m.w, format, args...)
+}
+
+func (m *metricsWriter) flush() error {
+	if m.err != nil {
+		return m.err
+	}
+	return m.w.Flush()
+}
+
+// labels formats label pairs as {name="value",...}.
+func labels(pairs ...string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(pairs[i])
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(pairs[i+1]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+
+	return b.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// sortedHosts returns hosts of histograms in stable order.
+func sortedHosts(histograms map[string]HistogramSnapshot) []string {
+	keys := make([]string, 0, len(histograms))
+	for key := range histograms {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
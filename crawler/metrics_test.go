@@ -0,0 +1,67 @@
+package crawler
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	stat := NewStat()
+	stat.AddTotalFetched()
+	stat.AddQueued(2)
+	stat.AddInFlight(1)
+	stat.AddFetchError(PhaseTimeout)
+	stat.AddSkipped(SkipAlreadyCrawled)
+	stat.AddResponse(`velikodny.com`, 200, "text/html", 10, 20*time.Millisecond)
+	stat.AddResponse(`"quoted"`, 404, "", 5, 3*time.Second)
+
+	var b bytes.Buffer
+	assert.NoError(t, WriteMetrics(&b, stat.Snapshot()))
+	metrics := b.String()
+
+	for _, line := range []string{
+		"# TYPE crawler_fetched_total counter\ncrawler_fetched_total 1\n",
+		"# TYPE crawler_queue_length gauge\ncrawler_queue_length 2\n",
+		"crawler_in_flight 1\n",
+		"crawler_bytes_total 15\n",
+		"crawler_responses_total{code=\"200\"} 1\ncrawler_responses_total{code=\"404\"} 1\n",
+		"crawler_responses_by_content_type_total{content_type=\"unknown\"} 1\n",
+		"crawler_fetch_errors_total{class=\"timeout\"} 1\n",
+		"crawler_skipped_total{reason=\"already_crawled\"} 1\n",
+		"# TYPE crawler_response_seconds histogram\n",
+		"crawler_response_seconds_bucket{host=\"velikodny.com\",le=\"0.01\"} 0\n",
+		"crawler_response_seconds_bucket{host=\"velikodny.com\",le=\"0.025\"} 1\n",
+		"crawler_response_seconds_bucket{host=\"velikodny.com\",le=\"+Inf\"} 1\n",
+		"crawler_response_seconds_sum{host=\"velikodny.com\"} 0.02\n",
+		"crawler_response_seconds_bucket{host=\"\\\"quoted\\\"\",le=\"2.5\"} 0\n",
+		"crawler_response_seconds_count{host=\"\\\"quoted\\\"\"} 1\n",
+	} {
+		assert.Contains(t, metrics, line)
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	server := newTestSite()
+	defer server.Close()
+
+	crawler := New(WithConcurrency(2))
+	visitAll(crawler)
+	assert.NoError(t, crawler.Run(server.URL+"/"))
+	crawler.Wait()
+
+	recorder := httptest.NewRecorder()
+	NewMetricsHandler(crawler.Stat()).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := ioutil.ReadAll(recorder.Body)
+
+	assert.Equal(t, MetricsContentType, recorder.Header().Get("Content-Type"))
+	assert.Contains(t, string(body), "crawler_fetched_total 3\n")
+	assert.Contains(t, string(body), "crawler_queue_length 0\n")
+	assert.Contains(t, string(body), "crawler_in_flight 0\n")
+	assert.Contains(t, string(body), "crawler_response_seconds_count{host=\""+server.Listener.Addr().String()+"\"} 4\n")
+}
@@ -0,0 +1,335 @@
+package crawler
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	mirrorIndexFile = "index.html"
+	// mirrorMaxName limits file name length in bytes, most file systems allow 255
+	mirrorMaxName = 200
+)
+
+// Mirror saves fetched pages and resources to directory tree mirroring URL paths,
+// like `wget --mirror`. Links between saved files are known only when crawling is finished,
+// so href and src attributes of saved HTML pages are rewritten to local files on Close.
+type Mirror struct {
+	mux sync.Mutex
+	// dir to save files to
+	dir string
+	// files maps URL to file path relative to dir
+	files map[string]string
+	// usedFiles and usedDirs hold case-folded paths taken by files and directories
+	usedFiles map[string]struct{}
+	usedDirs  map[string]struct{}
+	// pages holds saved HTML pages
+	pages []mirrorPage
+	// processor resolves links found on saved pages the same way extractor does
+	processor LinkProcessor
+}
+
+// mirrorPage is saved HTML page, its relative links are resolved against final URL.
+type mirrorPage struct {
+	url      string
+	finalURL string
+}
+
+// NewMirror creates Mirror saving files to dir.
+func NewMirror(dir string) (*Mirror, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &Mirror{
+		dir:       dir,
+		files:     make(map[string]string),
+		usedFiles: make(map[string]struct{}),
+		usedDirs:  make(map[string]struct{}),
+		processor: NewLinkProcessor(),
+	}, nil
+}
+
+// Save writes fetched response to file mapped from rawURL.
+func (m *Mirror) Save(rawURL string, response *Response) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	isPage := isHTML(response.ContentType)
+
+	m.mux.Lock()
+	name, ok := m.files[rawURL]
+	if !ok {
+		name = m.assign(u, isPage)
+		m.files[rawURL] = name
+		if isPage {
+			finalURL := response.FinalURL
+			if finalURL == "" {
+				finalURL = rawURL
+			}
+			m.pages = append(m.pages, mirrorPage{url: rawURL, finalURL: finalURL})
+		}
+	}
+	// links to redirect source and target lead to the same file
+	if _, ok := m.files[response.FinalURL]; !ok && response.FinalURL != "" {
+		m.files[response.FinalURL] = name
+	}
+	m.mux.Unlock()
+
+	filename := filepath.Join(m.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filename, response.Body, 0644)
+}
+
+// Close rewrites links of saved HTML pages to saved files,
+// links to resources which were not saved are made absolute.
+func (m *Mirror) Close() error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	for _, page := range m.pages {
+		if err := m.rewrite(page); err != nil {
+			return fmt.Errorf("rewrite links of '%s': %w", page.url, err)
+		}
+	}
+
+	return nil
+}
+
+// rewrite rewrites links of saved page, they are resolved against base URL of page.
+// Base element is dropped, as rewritten links are relative to page file.
+func (m *Mirror) rewrite(page mirrorPage) error {
+	name := m.files[page.url]
+	filename := filepath.Join(m.dir, filepath.FromSlash(name))
+
+	body, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	base, err := url.Parse(page.finalURL)
+	if err != nil {
+		return err
+	}
+	source, _ := NewLink(page.finalURL)
+	if href, ok := baseHref(body); ok {
+		source, _ = NewLink(resolveRef(base, href))
+	}
+
+	var b bytes.Buffer
+	z := html.NewTokenizer(bytes.NewReader(body))
+
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+
+		raw := append([]byte(nil), z.Raw()...)
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			b.Write(raw)
+			continue
+		}
+
+		token := z.Token()
+		if token.DataAtom == atom.Base {
+			continue
+		}
+
+		changed := false
+		for i, attr := range token.Attr {
+			if attr.Key != "href" && attr.Key != "src" {
+				continue
+			}
+			if ref, ok := m.localRef(source, name, attr.Val); ok {
+				token.Attr[i].Val = ref
+				changed = true
+			}
+		}
+
+		if changed {
+			b.WriteString(token.String())
+		} else {
+			b.Write(raw)
+		}
+	}
+
+	return ioutil.WriteFile(filename, b.Bytes(), 0644)
+}
+
+// baseHref returns href of the first base element of page.
+func baseHref(body []byte) (string, bool) {
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return "", false
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := z.Token()
+			if token.DataAtom != atom.Base {
+				continue
+			}
+			if href, ok := extractAttrs(token)["href"]; ok {
+				return href, true
+			}
+		}
+	}
+}
+
+// localRef returns reference to saved file relative to page file, or absolute URL if target was not saved.
+func (m *Mirror) localRef(source *Link, pageName string, ref string) (string, bool) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(ref, "#") {
+		return "", false
+	}
+
+	link := NewHrefLink(source, ref)
+	fragment := ""
+	if u, err := url.Parse(ref); err == nil && u.Fragment != "" {
+		// URL of fragment only is formatted as escaped fragment, EscapedFragment requires Go 1.15
+		fragment = (&url.URL{Fragment: u.Fragment}).String()
+	}
+
+	m.processor.Process(link)
+	if link.IsRejected() {
+		return "", false
+	}
+
+	target, ok := m.files[link.Url.String()]
+	if !ok {
+		return link.Url.String() + fragment, true
+	}
+
+	rel, err := filepath.Rel(filepath.Dir(filepath.FromSlash(pageName)), filepath.FromSlash(target))
+	if err != nil {
+		return "", false
+	}
+
+	segments := strings.Split(filepath.ToSlash(rel), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return strings.Join(segments, "/") + fragment, true
+}
+
+// assign maps URL to free file path, must be called with mux locked.
+func (m *Mirror) assign(u *url.URL, isPage bool) string {
+	dirs, name := mirrorPath(u, isPage)
+
+	// directory can't be created where file was already saved
+	for i := range dirs {
+		for {
+			if _, ok := m.usedFiles[foldPath(path.Join(dirs[:i+1]...))]; !ok {
+				break
+			}
+			dirs[i] += "_"
+		}
+	}
+
+	dir := path.Join(dirs...)
+	candidate := path.Join(dir, name)
+	for n := 1; m.used(candidate); n++ {
+		ext := path.Ext(name)
+		candidate = path.Join(dir, fmt.Sprintf("%s-%d%s", strings.TrimSuffix(name, ext), n, ext))
+	}
+
+	for i := range dirs {
+		m.usedDirs[foldPath(path.Join(dirs[:i+1]...))] = struct{}{}
+	}
+	m.usedFiles[foldPath(candidate)] = struct{}{}
+
+	return candidate
+}
+
+func (m *Mirror) used(name string) bool {
+	key := foldPath(name)
+	_, isFile := m.usedFiles[key]
+	_, isDir := m.usedDirs[key]
+	return isFile || isDir
+}
+
+// foldPath folds path case, so files don't collide on case-insensitive file systems.
+func foldPath(name string) string {
+	return strings.ToLower(name)
+}
+
+// mirrorPath maps URL to directories and file name: host first, then path segments.
+// Directory index pages are saved as index.html, query string is replaced with its hash
+// and HTML pages get .html extension.
+func mirrorPath(u *url.URL, isPage bool) ([]string, string) {
+	dirs := []string{safeFileName(strings.Replace(u.Host, ":", "_", 1))}
+
+	segments := strings.Split(strings.TrimPrefix(u.Path, "/"), "/")
+	for _, segment := range segments[:len(segments)-1] {
+		if segment != "" {
+			dirs = append(dirs, safeFileName(segment))
+		}
+	}
+
+	name := segments[len(segments)-1]
+	if name == "" {
+		name = mirrorIndexFile
+	}
+
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	if u.RawQuery != "" {
+		sum := md5.Sum([]byte(u.RawQuery))
+		base += "@" + hex.EncodeToString(sum[:4])
+	}
+	if isPage && ext != ".html" && ext != ".htm" {
+		base += ext
+		ext = ".html"
+	}
+
+	return dirs, safeFileName(base) + safeFileName(ext)
+}
+
+// safeFileName replaces characters not allowed in file names, keeping non-ASCII letters.
+func safeFileName(name string) string {
+	if name == "." || name == ".." {
+		return "_"
+	}
+
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r == utf8.RuneError, unicode.IsControl(r), strings.ContainsRune(`/\:*?"<>|`, r):
+			b.WriteRune('_')
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	safe := b.String()
+	if len(safe) > mirrorMaxName {
+		sum := md5.Sum([]byte(safe))
+		cut := mirrorMaxName
+		for !utf8.RuneStart(safe[cut]) {
+			cut--
+		}
+		safe = safe[:cut] + "~" + hex.EncodeToString(sum[:4])
+	}
+
+	return safe
+}
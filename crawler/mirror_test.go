@@ -0,0 +1,112 @@
+package crawler
+
+import (
+	"io/ioutil"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMirrorPath(t *testing.T) {
+	tests := []struct {
+		url    string
+		isPage bool
+		output string
+	}{
+		{"https://velikodny.com", true, "velikodny.com/index.html"},
+		{"https://velikodny.com/", true, "velikodny.com/index.html"},
+		{"https://velikodny.com/blog/", true, "velikodny.com/blog/index.html"},
+		{"https://velikodny.com/blog/post", true, "velikodny.com/blog/post.html"},
+		{"https://velikodny.com/blog/post.php", true, "velikodny.com/blog/post.php.html"},
+		{"https://velikodny.com/a.html", true, "velikodny.com/a.html"},
+		{"https://velikodny.com/img/1.png", false, "velikodny.com/img/1.png"},
+		{"https://velikodny.com/list?page=2", true, "velikodny.com/list@46589c7a.html"},
+		{"https://velikodny.com:8080/a:b*c", false, "velikodny.com_8080/a_b_c"},
+		{"https://velikodny.com/%D0%BF%D1%80%D0%B8%D0%B2%D0%B5%D1%82/", true, "velikodny.com/привет/index.html"},
+	}
+
+	for _, test := range tests {
+		u, err := url.Parse(test.url)
+		assert.NoError(t, err)
+
+		dirs, name := mirrorPath(u, test.isPage)
+		assert.Equal(t, test.output, path.Join(append(dirs, name)...), test.url)
+	}
+}
+
+func TestSafeFileName(t *testing.T) {
+	assert.Equal(t, "_", safeFileName(".."))
+	assert.Equal(t, "a_b", safeFileName("a\x00b"))
+
+	long := safeFileName(strings.Repeat("я", 150))
+	assert.True(t, len(long) <= mirrorMaxName+9)
+	assert.NotEqual(t, long, safeFileName(strings.Repeat("я", 151)))
+}
+
+func TestMirror(t *testing.T) {
+	dir, remove := tempDir(t)
+	defer remove()
+	mirror, err := NewMirror(dir)
+	assert.NoError(t, err)
+
+	save := func(rawURL, contentType, body string) {
+		assert.NoError(t, mirror.Save(rawURL, &Response{
+			ContentType: contentType,
+			FinalURL:    rawURL,
+			Body:        []byte(body),
+		}))
+	}
+
+	save("https://velikodny.com/", "text/html", `<a href="/blog/post#top">post</a><img src="img/1.png" alt="x"><a href="/missing">missing</a>`)
+	save("https://velikodny.com/blog/post", "text/html", `<a href="/">home</a><a href="/Blog/Post">other</a>`)
+	save("https://velikodny.com/Blog/Post", "text/html", `<p>case collision</p>`)
+	save("https://velikodny.com/img/1.png", "image/png", "png")
+	assert.NoError(t, mirror.Close())
+
+	read := func(name string) string {
+		b, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		assert.NoError(t, err)
+		return string(b)
+	}
+
+	assert.Equal(t, `<a href="blog/post.html#top">post</a><img src="img/1.png" alt="x"><a href="https://velikodny.com/missing">missing</a>`, read("velikodny.com/index.html"))
+	assert.Equal(t, `<a href="../index.html">home</a><a href="../Blog/Post-1.html">other</a>`, read("velikodny.com/blog/post.html"))
+	assert.Equal(t, "png", read("velikodny.com/img/1.png"))
+}
+
+func TestMirror_Redirect(t *testing.T) {
+	dir, remove := tempDir(t)
+	defer remove()
+	mirror, err := NewMirror(dir)
+	assert.NoError(t, err)
+
+	save := func(rawURL, finalURL, contentType, body string) {
+		assert.NoError(t, mirror.Save(rawURL, &Response{
+			ContentType: contentType,
+			FinalURL:    finalURL,
+			Body:        []byte(body),
+		}))
+	}
+
+	// links of redirected page are relative to its final URL
+	save("https://velikodny.com/old", "https://velikodny.com/docs/new", "text/html", `<a href="guide">guide</a><a href="../">home</a>`)
+	save("https://velikodny.com/docs/guide", "https://velikodny.com/docs/guide", "text/html",
+		`<head><base href="/static/"></head><img src="logo.png"><a href="/docs/new">new</a>`)
+	save("https://velikodny.com/", "https://velikodny.com/", "text/html", `<p>home</p>`)
+	save("https://velikodny.com/static/logo.png", "https://velikodny.com/static/logo.png", "image/png", "png")
+	assert.NoError(t, mirror.Close())
+
+	read := func(name string) string {
+		b, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		assert.NoError(t, err)
+		return string(b)
+	}
+
+	assert.Equal(t, `<a href="docs/guide.html">guide</a><a href="index.html">home</a>`, read("velikodny.com/old.html"))
+	// base element is honoured and dropped, as rewritten links are relative to page file
+	assert.Equal(t, `<head></head><img src="../static/logo.png"><a href="../old.html">new</a>`, read("velikodny.com/docs/guide.html"))
+}
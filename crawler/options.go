@@ -3,6 +3,7 @@ package crawler
 import (
 	"context"
 	"net/http"
+	"time"
 )
 
 type Option func(c *Crawler)
@@ -19,10 +20,13 @@ func WithClient(client *http.Client) Option {
 	}
 }
 
+// WithConcurrency sets number of concurrent fetchers, DefaultConcurrency is used if it's less than 1.
 func WithConcurrency(threadsNum int) Option {
 	return func(c *Crawler) {
+		if threadsNum < 1 {
+			threadsNum = DefaultConcurrency
+		}
 		c.cfg.concurrency = threadsNum
-		c.fetchersLimit = initChanCapacity(threadsNum)
 	}
 }
 
@@ -34,10 +38,10 @@ func WithAllowedDomains(allowedDomains ...string) Option {
 	}
 }
 
-// Statistic sets 3rd-party Stat interface implementation for Crawler.
+// Statistic sets 3rd-party Stat interface implementation for Crawler, see DetailedStat.
 func WithStatistic(stat Stat) Option {
 	return func(c *Crawler) {
-		c.stat = stat
+		c.stat = detailed(stat)
 	}
 }
 
@@ -48,6 +52,157 @@ func WithExtractor(extractor Extractor) Option {
 	}
 }
 
+// WithOutput adds outputs receiving records of fetched pages and discovered links.
+func WithOutput(outputs ...Output) Option {
+	return func(c *Crawler) {
+		c.outputs = append(c.outputs, outputs...)
+	}
+}
+
+// WithSEOAudit runs on-page audit of fetched HTML pages.
+func WithSEOAudit(audit *SEOAudit) Option {
+	return func(c *Crawler) {
+		c.onFetchedHandler = append(c.onFetchedHandler, audit.Inspect)
+		c.outputs = append(c.outputs, audit)
+	}
+}
+
+// WithWARC archives every fetched resource with WARC writer.
+func WithWARC(warc *WARCWriter) Option {
+	return func(c *Crawler) {
+		c.warc = warc
+	}
+}
+
+// WithMirror saves every successfully fetched resource to mirror.
+func WithMirror(mirror *Mirror) Option {
+	return func(c *Crawler) {
+		c.mirror = mirror
+	}
+}
+
+// WithCache wraps client transport with on-disk cache, so recrawls fetch only changed pages.
+func WithCache(cache *Cache) Option {
+	return func(c *Crawler) {
+		c.cache = cache
+	}
+}
+
+// WithPageText adds visible text of HTML pages to page records, so changes of pages can be compared between crawls.
+func WithPageText() Option {
+	return func(c *Crawler) {
+		c.cfg.pageText = true
+	}
+}
+
+// WithPageMeta extracts metadata of HTML pages, e.g. title, Open Graph tags and headings,
+// to Response.Meta and page records once, so handlers don't parse pages again.
+func WithPageMeta() Option {
+	return func(c *Crawler) {
+		c.cfg.pageMeta = true
+	}
+}
+
+// WithCookieJar sets cookie jar shared by all fetchers, see NewCookieJar.
+func WithCookieJar(jar http.CookieJar) Option {
+	return func(c *Crawler) {
+		c.jar = jar
+	}
+}
+
+// WithLogin logs in with form before crawling and again when session expires.
+// Cookie jar is created if none is set.
+func WithLogin(login *Login) Option {
+	return func(c *Crawler) {
+		c.login = login
+	}
+}
+
+// WithUserAgent sets User-Agent header of requests.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Crawler) {
+		c.headers.set("", "User-Agent", userAgent)
+	}
+}
+
+// WithAcceptLanguage sets Accept-Language header of requests, e.g. "en-US,en;q=0.9".
+func WithAcceptLanguage(languages string) Option {
+	return func(c *Crawler) {
+		c.headers.set("", "Accept-Language", languages)
+	}
+}
+
+// WithHeader sets header of requests to all hosts.
+func WithHeader(key, value string) Option {
+	return func(c *Crawler) {
+		c.headers.set("", key, value)
+	}
+}
+
+// WithHostHeader sets header of requests to host only, host may include port.
+func WithHostHeader(host, key, value string) Option {
+	return func(c *Crawler) {
+		c.headers.set(host, key, value)
+	}
+}
+
+// WithBasicAuth authenticates requests to host with HTTP Basic auth, host may include port.
+func WithBasicAuth(host, username, password string) Option {
+	return func(c *Crawler) {
+		c.headers.set(host, "Authorization", basicAuth(username, password))
+	}
+}
+
+// WithBearerToken authenticates requests to host with bearer token, host may include port.
+func WithBearerToken(host, token string) Option {
+	return func(c *Crawler) {
+		c.headers.set(host, "Authorization", "Bearer "+token)
+	}
+}
+
+// WithProxies sends requests through proxies of pool, client transport must be *http.Transport or nil,
+// otherwise requests fail with ErrUnsupportedTransport.
+func WithProxies(pool *ProxyPool) Option {
+	return func(c *Crawler) {
+		c.proxies = pool
+	}
+}
+
+// WithMaxPages limits number of HTML pages fetched successfully, failed fetches and other resources are not counted.
+func WithMaxPages(pages int64) Option {
+	return func(c *Crawler) {
+		c.cfg.maxPages = pages
+	}
+}
+
+// WithMaxBytes stops crawling once bytes of response bodies are downloaded.
+func WithMaxBytes(bytes int64) Option {
+	return func(c *Crawler) {
+		c.cfg.maxBytes = bytes
+	}
+}
+
+// WithMaxDuration stops crawling once duration passed since Run.
+func WithMaxDuration(duration time.Duration) Option {
+	return func(c *Crawler) {
+		c.cfg.maxDuration = duration
+	}
+}
+
+// WithMaxPagesPerHost limits number of HTML pages fetched successfully from each host.
+func WithMaxPagesPerHost(pages int) Option {
+	return func(c *Crawler) {
+		c.cfg.maxHostPages = pages
+	}
+}
+
+// WithMaxBodySize limits size of response body, larger responses fail with ErrTooLarge.
+func WithMaxBodySize(size int64) Option {
+	return func(c *Crawler) {
+		c.cfg.maxBodySize = size
+	}
+}
+
 func initChanCapacity(threadsNum int) (sem chan struct{}) {
 	sem = make(chan struct{}, threadsNum)
 	for i := 0; i < threadsNum; i++ {
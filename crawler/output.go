@@ -0,0 +1,137 @@
+package crawler
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Output receives crawl records. Crawler writes records from fetchers goroutines,
+// so implementations must be safe for concurrent use.
+type Output interface {
+	// WritePage writes record of fetched URL.
+	WritePage(record *PageRecord) error
+	// WriteLink writes link discovered on fetched page.
+	WriteLink(link *Link) error
+	// Close flushes buffered records.
+	Close() error
+}
+
+// pageInfoOutput is implemented by outputs which know whether they use title, description and
+// canonical URL of page records. Page head is parsed for records only if some output uses them,
+// outputs not implementing it are assumed to use them.
+type pageInfoOutput interface {
+	usesPageInfo() bool
+}
+
+// needPageInfo checks some of outputs uses title, description or canonical URL of page records.
+func needPageInfo(outputs []Output) bool {
+	for _, output := range outputs {
+		if o, ok := output.(pageInfoOutput); !ok || o.usesPageInfo() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PageRecord describes single fetched URL.
+type PageRecord struct {
+	URL           string `json:"url"`
+	FinalURL      string `json:"final_url"`
+	StatusCode    int    `json:"status"`
+	ContentType   string `json:"content_type"`
+	ContentLength int    `json:"content_length"`
+	Depth         int    `json:"depth"`
+	// FetchedAt holds the time fetching was finished.
+	FetchedAt time.Time `json:"fetched_at"`
+	// Duration holds the time spent to fetch URL, in nanoseconds.
+	Duration time.Duration `json:"duration"`
+	// Timing holds breakdown of duration by request phases, in nanoseconds.
+	Timing      *Timing `json:"timing,omitempty"`
+	Title       string  `json:"title,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Canonical   string  `json:"canonical,omitempty"`
+	// CacheHit is set if response was served from cache, so duration and timing don't measure fetching page.
+	CacheHit bool `json:"cache_hit,omitempty"`
+	// ContentMd5 holds hash of response body, used to detect changed pages between crawls.
+	ContentMd5 string `json:"content_md5,omitempty"`
+	// Text holds visible text of HTML page, set if crawler runs WithPageText.
+	Text  string `json:"text,omitempty"`
+	Error string `json:"error,omitempty"`
+	// ErrorClass is phase fetch failed in, see FetchPhase.
+	ErrorClass FetchPhase `json:"error_class,omitempty"`
+	Outlinks   []string   `json:"outlinks,omitempty"`
+	// Meta holds metadata of HTML page, set if crawler runs WithPageMeta.
+	Meta *PageMeta `json:"meta,omitempty"`
+	// Analysis holds link analysis of the page, set by AnalyzedOutput.
+	Analysis *PageAnalysis `json:"analysis,omitempty"`
+}
+
+// RedirectTarget returns URL request was redirected to, or empty string if there was no redirect.
+func (r *PageRecord) RedirectTarget() string {
+	if r.FinalURL == "" || r.FinalURL == r.URL {
+		return ""
+	}
+
+	return r.FinalURL
+}
+
+// newPageRecord builds record from fetched response, response may be nil if request failed.
+// Page head is parsed for title, description and canonical URL only if pageInfo is set.
+func newPageRecord(rawURL string, depth int, response *Response, links []*Link, fetchErr error, pageInfo bool) *PageRecord {
+	record := &PageRecord{
+		URL:       rawURL,
+		Depth:     depth,
+		FetchedAt: time.Now(),
+	}
+
+	if response != nil {
+		record.FinalURL = response.FinalURL
+		record.StatusCode = response.StatusCode
+		record.ContentType = response.ContentType
+		record.ContentLength = response.ContentLength
+		record.Duration = response.Duration
+		timing := response.Timing
+		record.Timing = &timing
+		record.CacheHit = response.CacheHit
+		if response.Body != nil {
+			record.ContentMd5 = response.Md5()
+		}
+
+		// metadata is extracted once if crawler runs WithPageMeta
+		if response.Meta != nil {
+			record.Title = response.Meta.Title
+			record.Description = response.Meta.Description
+			record.Canonical = response.Meta.Canonical
+			record.Meta = response.Meta
+		} else if pageInfo && fetchErr == nil && isHTML(response.ContentType) {
+			base, err := url.Parse(response.FinalURL)
+			if err != nil {
+				base = &url.URL{}
+			}
+			info := extractPageInfo(response.Body, base)
+			record.Title = strings.TrimSpace(info.Title)
+			record.Description = info.Description
+			record.Canonical = info.Canonical
+		}
+	}
+
+	if fetchErr != nil {
+		record.Error = fetchErr.Error()
+
+		var err *FetchError
+		if errors.As(fetchErr, &err) {
+			record.ErrorClass = err.Phase
+		}
+	}
+
+	for _, link := range links {
+		if !link.IsRejected() {
+			record.Outlinks = append(record.Outlinks, link.Url.String())
+		}
+	}
+
+	return record
+}
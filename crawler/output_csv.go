@@ -0,0 +1,224 @@
+package crawler
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrUnknownCSVColumn = errors.New("unknown CSV column")
+)
+
+// CSVColumn names column of CSV output.
+type CSVColumn string
+
+const (
+	CSVURL             CSVColumn = "url"
+	CSVStatus          CSVColumn = "status"
+	CSVContentType     CSVColumn = "content_type"
+	CSVSize            CSVColumn = "size"
+	CSVDepth           CSVColumn = "depth"
+	CSVTitle           CSVColumn = "title"
+	CSVMetaDescription CSVColumn = "meta_description"
+	CSVCanonical       CSVColumn = "canonical"
+	CSVInlinks         CSVColumn = "inlinks"
+	CSVResponseTime    CSVColumn = "response_time_ms"
+	CSVRedirectTarget  CSVColumn = "redirect_target"
+	CSVDNSTime         CSVColumn = "dns_ms"
+	CSVConnectTime     CSVColumn = "connect_ms"
+	CSVTLSTime         CSVColumn = "tls_ms"
+	CSVTTFB            CSVColumn = "ttfb_ms"
+	CSVTransferTime    CSVColumn = "transfer_ms"
+	CSVConnReused      CSVColumn = "conn_reused"
+)
+
+// DefaultCSVColumns holds all supported CSV columns.
+var DefaultCSVColumns = []CSVColumn{
+	CSVURL,
+	CSVStatus,
+	CSVContentType,
+	CSVSize,
+	CSVDepth,
+	CSVTitle,
+	CSVMetaDescription,
+	CSVCanonical,
+	CSVInlinks,
+	CSVResponseTime,
+	CSVRedirectTarget,
+	CSVDNSTime,
+	CSVConnectTime,
+	CSVTLSTime,
+	CSVTTFB,
+	CSVTransferTime,
+	CSVConnReused,
+}
+
+// ParseCSVColumns parses comma separated list of column names, e.g. "url,status,title".
+func ParseCSVColumns(s string) ([]CSVColumn, error) {
+	var columns []CSVColumn
+
+	for _, name := range strings.Split(s, ",") {
+		column := CSVColumn(strings.TrimSpace(name))
+		if !column.valid() {
+			return nil, fmt.Errorf("column '%s': %w", column, ErrUnknownCSVColumn)
+		}
+		columns = append(columns, column)
+	}
+
+	return columns, nil
+}
+
+func (column CSVColumn) valid() bool {
+	for _, c := range DefaultCSVColumns {
+		if c == column {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewCSVOutput creates Output writing one CSV row per fetched page to w.
+// Inlinks are known only when crawling is finished, so rows are buffered
+// and written on Close. All columns are written if none passed. Close does not close w.
+func NewCSVOutput(w io.Writer, columns ...CSVColumn) Output {
+	if len(columns) == 0 {
+		columns = DefaultCSVColumns
+	}
+
+	return &csvOutput{
+		w:       csv.NewWriter(w),
+		columns: columns,
+		inlinks: make(map[string]map[string]struct{}),
+	}
+}
+
+type csvOutput struct {
+	mux     sync.Mutex
+	w       *csv.Writer
+	columns []CSVColumn
+	records []*PageRecord
+	// inlinks holds pages linking to URL
+	inlinks map[string]map[string]struct{}
+}
+
+func (o *csvOutput) usesPageInfo() bool {
+	for _, column := range o.columns {
+		if column == CSVTitle || column == CSVMetaDescription || column == CSVCanonical {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (o *csvOutput) WritePage(record *PageRecord) error {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
+	o.records = append(o.records, record)
+	for _, outlink := range record.Outlinks {
+		if outlink == record.URL {
+			continue
+		}
+		if _, ok := o.inlinks[outlink]; !ok {
+			o.inlinks[outlink] = make(map[string]struct{})
+		}
+		o.inlinks[outlink][record.URL] = struct{}{}
+	}
+
+	return nil
+}
+
+func (o *csvOutput) WriteLink(*Link) error {
+	return nil
+}
+
+func (o *csvOutput) Close() error {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
+	header := make([]string, len(o.columns))
+	for i, column := range o.columns {
+		header[i] = string(column)
+	}
+	if err := o.w.Write(header); err != nil {
+		return err
+	}
+
+	for _, record := range o.records {
+		if err := o.w.Write(o.row(record)); err != nil {
+			return err
+		}
+	}
+	o.records = nil
+
+	o.w.Flush()
+	return o.w.Error()
+}
+
+func (o *csvOutput) row(record *PageRecord) []string {
+	row := make([]string, len(o.columns))
+
+	for i, column := range o.columns {
+		switch column {
+		case CSVURL:
+			row[i] = record.URL
+		case CSVStatus:
+			row[i] = strconv.Itoa(record.StatusCode)
+		case CSVContentType:
+			row[i] = record.ContentType
+		case CSVSize:
+			row[i] = strconv.Itoa(record.ContentLength)
+		case CSVDepth:
+			row[i] = strconv.Itoa(record.Depth)
+		case CSVTitle:
+			row[i] = record.Title
+		case CSVMetaDescription:
+			row[i] = record.Description
+		case CSVCanonical:
+			row[i] = record.Canonical
+		case CSVInlinks:
+			if record.Analysis != nil {
+				row[i] = strconv.Itoa(record.Analysis.Inlinks)
+			} else {
+				row[i] = strconv.Itoa(len(o.inlinks[record.URL]))
+			}
+		case CSVResponseTime:
+			row[i] = strconv.FormatInt(record.Duration.Milliseconds(), 10)
+		case CSVRedirectTarget:
+			row[i] = record.RedirectTarget()
+		case CSVDNSTime, CSVConnectTime, CSVTLSTime, CSVTTFB, CSVTransferTime, CSVConnReused:
+			row[i] = timingValue(record.Timing, column)
+		}
+	}
+
+	return row
+}
+
+// timingValue formats request phase column, empty if request failed.
+func timingValue(timing *Timing, column CSVColumn) string {
+	if timing == nil {
+		return ""
+	}
+
+	switch column {
+	case CSVDNSTime:
+		return strconv.FormatInt(timing.DNS.Milliseconds(), 10)
+	case CSVConnectTime:
+		return strconv.FormatInt(timing.Connect.Milliseconds(), 10)
+	case CSVTLSTime:
+		return strconv.FormatInt(timing.TLS.Milliseconds(), 10)
+	case CSVTTFB:
+		return strconv.FormatInt(timing.TTFB.Milliseconds(), 10)
+	case CSVTransferTime:
+		return strconv.FormatInt(timing.Transfer.Milliseconds(), 10)
+	default:
+		return strconv.FormatBool(timing.ConnReused)
+	}
+}
@@ -0,0 +1,59 @@
+package crawler
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCSVColumns(t *testing.T) {
+	columns, err := ParseCSVColumns("url, status,title")
+	assert.NoError(t, err)
+	assert.Equal(t, []CSVColumn{CSVURL, CSVStatus, CSVTitle}, columns)
+
+	_, err = ParseCSVColumns("url,unknown")
+	assert.True(t, errors.Is(err, ErrUnknownCSVColumn))
+}
+
+func TestCSVOutput(t *testing.T) {
+	var b bytes.Buffer
+	output := NewCSVOutput(&b, CSVURL, CSVStatus, CSVTitle, CSVInlinks, CSVResponseTime, CSVRedirectTarget)
+
+	assert.NoError(t, output.WritePage(&PageRecord{
+		URL:        "https://velikodny.com/",
+		FinalURL:   "https://velikodny.com/",
+		StatusCode: 200,
+		Title:      `Home, "sweet" home`,
+		Duration:   1500 * time.Millisecond,
+		Outlinks:   []string{"https://velikodny.com/", "https://velikodny.com/a", "https://velikodny.com/old"},
+	}))
+	assert.NoError(t, output.WritePage(&PageRecord{
+		URL:        "https://velikodny.com/a",
+		FinalURL:   "https://velikodny.com/a",
+		StatusCode: 200,
+		Outlinks:   []string{"https://velikodny.com/old"},
+	}))
+	assert.NoError(t, output.WritePage(&PageRecord{
+		URL:        "https://velikodny.com/old",
+		FinalURL:   "https://velikodny.com/new",
+		StatusCode: 200,
+	}))
+	assert.Equal(t, 0, b.Len())
+	assert.NoError(t, output.Close())
+
+	assert.Equal(t, `url,status,title,inlinks,response_time_ms,redirect_target
+https://velikodny.com/,200,"Home, ""sweet"" home",0,1500,
+https://velikodny.com/a,200,,1,0,
+https://velikodny.com/old,200,,2,0,https://velikodny.com/new
+`, b.String())
+}
+
+func TestCSVOutput_UsesPageInfo(t *testing.T) {
+	var b bytes.Buffer
+	assert.True(t, needPageInfo([]Output{NewCSVOutput(&b)}))
+	assert.True(t, needPageInfo([]Output{NewCSVOutput(&b, CSVURL, CSVCanonical)}))
+	assert.False(t, needPageInfo([]Output{NewCSVOutput(&b, CSVURL, CSVStatus), NewGraph()}))
+}
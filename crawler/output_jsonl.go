@@ -0,0 +1,59 @@
+package crawler
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+const (
+	jsonlPageType = "page"
+	jsonlLinkType = "link"
+)
+
+type jsonlPage struct {
+	Type string `json:"type"`
+	*PageRecord
+}
+
+type jsonlLink struct {
+	Type string `json:"type"`
+	*Link
+}
+
+// NewJSONLOutput creates Output writing one JSON object per line to w.
+// Links are written only if withLinks is set. Close does not close w.
+func NewJSONLOutput(w io.Writer, withLinks bool) Output {
+	return &jsonlOutput{
+		enc:       json.NewEncoder(w),
+		withLinks: withLinks,
+	}
+}
+
+type jsonlOutput struct {
+	mux       sync.Mutex
+	enc       *json.Encoder
+	withLinks bool
+}
+
+func (o *jsonlOutput) WritePage(record *PageRecord) error {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
+	return o.enc.Encode(jsonlPage{Type: jsonlPageType, PageRecord: record})
+}
+
+func (o *jsonlOutput) WriteLink(link *Link) error {
+	if !o.withLinks {
+		return nil
+	}
+
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
+	return o.enc.Encode(jsonlLink{Type: jsonlLinkType, Link: link})
+}
+
+func (o *jsonlOutput) Close() error {
+	return nil
+}
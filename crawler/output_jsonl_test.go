@@ -0,0 +1,54 @@
+package crawler
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONLOutput(t *testing.T) {
+	link := &Link{Source: "https://velikodny.com", RawRef: "mailto:me", Ref: "mailto:me"}
+	link.SetMalformed("scheme mailto not supported")
+	record := &PageRecord{
+		URL:        "https://velikodny.com",
+		FinalURL:   "https://velikodny.com/",
+		StatusCode: 200,
+		Outlinks:   []string{"https://velikodny.com/1"},
+	}
+
+	t.Run("pages only", func(t *testing.T) {
+		var b bytes.Buffer
+		output := NewJSONLOutput(&b, false)
+		assert.NoError(t, output.WritePage(record))
+		assert.NoError(t, output.WriteLink(link))
+		assert.NoError(t, output.Close())
+
+		lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+		assert.Equal(t, 1, len(lines))
+
+		var page map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(lines[0]), &page))
+		assert.Equal(t, "page", page["type"])
+		assert.Equal(t, "https://velikodny.com/", page["final_url"])
+		assert.Equal(t, float64(200), page["status"])
+	})
+	t.Run("with links", func(t *testing.T) {
+		var b bytes.Buffer
+		output := NewJSONLOutput(&b, true)
+		assert.NoError(t, output.WritePage(record))
+		assert.NoError(t, output.WriteLink(link))
+
+		lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+		assert.Equal(t, 2, len(lines))
+
+		var l map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(lines[1]), &l))
+		assert.Equal(t, "link", l["type"])
+		assert.Equal(t, "mailto:me", l["raw_ref"])
+		assert.Equal(t, true, l["malformed"])
+		assert.Equal(t, "scheme mailto not supported", l["error"])
+	})
+}
@@ -0,0 +1,66 @@
+package crawler
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// pageInfo holds page details written to page records.
+type pageInfo struct {
+	Title       string
+	Description string
+	Canonical   string
+}
+
+// extractPageInfo reads title, meta description and canonical URL from HTML page head,
+// canonical URL is resolved against base like in PageMeta.
+func extractPageInfo(body []byte, base *url.URL) pageInfo {
+	var info pageInfo
+
+	z := html.NewTokenizer(bytes.NewReader(body))
+	inTitle := false
+
+	for {
+		tt := z.Next()
+
+		switch tt {
+		case html.ErrorToken:
+			return info
+		case html.TextToken:
+			if inTitle {
+				info.Title += string(z.Text())
+			}
+		case html.EndTagToken:
+			token := z.Token()
+			switch token.Data {
+			case "title":
+				inTitle = false
+			case "head":
+				// everything we need is in head
+				return info
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := z.Token()
+
+			switch token.Data {
+			case "title":
+				inTitle = tt == html.StartTagToken && info.Title == ""
+			case "meta":
+				attrs := extractAttrs(token)
+				if strings.EqualFold(attrs["name"], "description") && info.Description == "" {
+					info.Description = attrs["content"]
+				}
+			case "link":
+				attrs := extractAttrs(token)
+				if href, ok := attrs["href"]; ok && hasToken(attrs["rel"], "canonical") && info.Canonical == "" {
+					info.Canonical = resolveRef(base, href)
+				}
+			case "body":
+				return info
+			}
+		}
+	}
+}
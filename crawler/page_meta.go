@@ -0,0 +1,179 @@
+package crawler
+
+import (
+	"bytes"
+	"mime"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// PageMeta holds metadata of HTML page, set on Response if crawler runs WithPageMeta.
+type PageMeta struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Canonical holds absolute canonical URL.
+	Canonical string `json:"canonical,omitempty"`
+	// Lang holds lang attribute of html tag.
+	Lang string `json:"lang,omitempty"`
+	// Charset is declared by meta tag, or by Content-Type header if page doesn't declare it.
+	Charset string `json:"charset,omitempty"`
+	// OpenGraph holds Open Graph tags by property, e.g. "og:title", the first tag of property is kept.
+	OpenGraph map[string]string `json:"open_graph,omitempty"`
+	// Twitter holds Twitter card tags by name, e.g. "twitter:card", the first tag of name is kept.
+	Twitter    map[string]string `json:"twitter,omitempty"`
+	Alternates []*Alternate      `json:"alternates,omitempty"`
+	// Headings holds h1-h6 headings in document order.
+	Headings []*Heading `json:"headings,omitempty"`
+	// ImagesMissingAlt holds sources of images without alt attribute.
+	ImagesMissingAlt []string `json:"images_missing_alt,omitempty"`
+}
+
+// H1 returns number of h1 headings.
+func (m *PageMeta) H1() int {
+	var n int
+	for _, heading := range m.Headings {
+		if heading.Level == 1 {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Alternate is hreflang alternate version of page.
+type Alternate struct {
+	Hreflang string `json:"hreflang"`
+	URL      string `json:"url"`
+}
+
+// Heading is heading of page outline.
+type Heading struct {
+	Level int    `json:"level"`
+	Text  string `json:"text"`
+}
+
+// ExtractPageMeta parses HTML page of response, relative URLs are resolved against final URL of response.
+func ExtractPageMeta(response *Response) *PageMeta {
+	meta := &PageMeta{}
+
+	doc, err := html.Parse(bytes.NewReader(response.Body))
+	if err != nil {
+		return meta
+	}
+
+	base, err := url.Parse(response.FinalURL)
+	if err != nil {
+		base = &url.URL{}
+	}
+	extractMeta(doc, meta, base)
+
+	if meta.Charset == "" {
+		if _, params, err := mime.ParseMediaType(response.ContentType); err == nil {
+			meta.Charset = strings.ToLower(params["charset"])
+		}
+	}
+
+	return meta
+}
+
+// extractMeta walks DOM filling meta, elements of SVG and MathML are skipped.
+func extractMeta(n *html.Node, meta *PageMeta, base *url.URL) {
+	if n.Type == html.ElementNode && n.Namespace == "" {
+		switch n.DataAtom {
+		case atom.Html:
+			meta.Lang = nodeAttrValue(n, "lang")
+		case atom.Title:
+			if meta.Title == "" {
+				meta.Title = normalizeSpace(nodeText(n))
+			}
+		case atom.Meta:
+			extractMetaTag(n, meta)
+		case atom.Link:
+			href, ok := nodeAttr(n, "href")
+			if !ok {
+				break
+			}
+			rel := nodeAttrValue(n, "rel")
+			if hasToken(rel, "canonical") && meta.Canonical == "" {
+				meta.Canonical = resolveRef(base, href)
+			}
+			if hreflang := nodeAttrValue(n, "hreflang"); hreflang != "" && hasToken(rel, "alternate") {
+				meta.Alternates = append(meta.Alternates, &Alternate{Hreflang: hreflang, URL: resolveRef(base, href)})
+			}
+		case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+			meta.Headings = append(meta.Headings, &Heading{Level: int(n.Data[1] - '0'), Text: normalizeSpace(deepText(n))})
+		case atom.Img:
+			if _, ok := nodeAttr(n, "alt"); !ok {
+				meta.ImagesMissingAlt = append(meta.ImagesMissingAlt, nodeAttrValue(n, "src"))
+			}
+		}
+	}
+
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		extractMeta(child, meta, base)
+	}
+}
+
+func extractMetaTag(n *html.Node, meta *PageMeta) {
+	if charset := nodeAttrValue(n, "charset"); charset != "" && meta.Charset == "" {
+		meta.Charset = strings.ToLower(strings.TrimSpace(charset))
+	}
+
+	content, ok := nodeAttr(n, "content")
+	if !ok {
+		return
+	}
+
+	if strings.EqualFold(nodeAttrValue(n, "http-equiv"), "content-type") && meta.Charset == "" {
+		if _, params, err := mime.ParseMediaType(content); err == nil {
+			meta.Charset = strings.ToLower(params["charset"])
+		}
+	}
+
+	name := strings.ToLower(nodeAttrValue(n, "name"))
+	// Open Graph tags use property attribute, though name is often used as well
+	property := strings.ToLower(nodeAttrValue(n, "property"))
+	if property == "" {
+		property = name
+	}
+
+	switch {
+	case name == "description" && meta.Description == "":
+		meta.Description = normalizeSpace(content)
+	case strings.HasPrefix(property, "og:"):
+		if meta.OpenGraph == nil {
+			meta.OpenGraph = make(map[string]string)
+		}
+		if _, ok := meta.OpenGraph[property]; !ok {
+			meta.OpenGraph[property] = content
+		}
+	case strings.HasPrefix(property, "twitter:"):
+		if meta.Twitter == nil {
+			meta.Twitter = make(map[string]string)
+		}
+		if _, ok := meta.Twitter[property]; !ok {
+			meta.Twitter[property] = content
+		}
+	}
+}
+
+// deepText returns text of node and its descendants.
+func deepText(n *html.Node) string {
+	var b strings.Builder
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+
+	return b.String()
+}
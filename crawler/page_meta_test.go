@@ -0,0 +1,93 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractPageMeta(t *testing.T) {
+	body := `<!DOCTYPE html><html lang="en-GB"><head>
+		<meta charset="UTF-8">
+		<title> Home &amp; garden </title>
+		<meta name="description" content="root page">
+		<link rel="canonical" href="/home#top">
+		<link rel="alternate" hreflang="de" href="/de/">
+		<link rel="alternate" hreflang="x-default" href="https://velikodny.com/">
+		<link rel="alternate" type="application/rss+xml" href="/feed">
+		<meta property="og:title" content="Home">
+		<meta property="og:image" content="/a.png">
+		<meta property="og:image" content="/b.png">
+		<meta name="twitter:card" content="summary">
+	</head><body>
+		<h1>Home <em>and</em> garden</h1><h2>Plants</h2><svg><title>icon</title></svg><h3>Trees</h3>
+		<img src="/tree.png" alt="tree"><img src="/leaf.png">
+	</body></html>`
+
+	meta := ExtractPageMeta(&Response{FinalURL: "https://velikodny.com/en/", ContentType: "text/html", Body: []byte(body)})
+
+	assert.Equal(t, &PageMeta{
+		Title:       "Home & garden",
+		Description: "root page",
+		Canonical:   "https://velikodny.com/home",
+		Lang:        "en-GB",
+		Charset:     "utf-8",
+		OpenGraph:   map[string]string{"og:title": "Home", "og:image": "/a.png"},
+		Twitter:     map[string]string{"twitter:card": "summary"},
+		Alternates: []*Alternate{
+			{Hreflang: "de", URL: "https://velikodny.com/de/"},
+			{Hreflang: "x-default", URL: "https://velikodny.com/"},
+		},
+		Headings:         []*Heading{{Level: 1, Text: "Home and garden"}, {Level: 2, Text: "Plants"}, {Level: 3, Text: "Trees"}},
+		ImagesMissingAlt: []string{"/leaf.png"},
+	}, meta)
+	assert.Equal(t, 1, meta.H1())
+}
+
+func TestExtractPageMeta_Charset(t *testing.T) {
+	header := ExtractPageMeta(&Response{ContentType: "text/html; charset=ISO-8859-1", Body: []byte(`<title>a</title>`)})
+	assert.Equal(t, "iso-8859-1", header.Charset)
+
+	httpEquiv := ExtractPageMeta(&Response{ContentType: "text/html; charset=utf-8", Body: []byte(`<meta http-equiv="Content-Type" content="text/html; charset=windows-1251">`)})
+	assert.Equal(t, "windows-1251", httpEquiv.Charset)
+}
+
+func TestCrawler_WithPageMeta(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html lang="en"><title>Home</title><link rel="canonical nofollow" href="/home#top"><h1>Home</h1></html>`)
+	}))
+	defer server.Close()
+
+	for _, withMeta := range []bool{false, true} {
+		var options []Option
+		if withMeta {
+			options = append(options, WithPageMeta())
+		}
+		output := &recordsOutput{records: make(map[string]*PageRecord)}
+		var meta *PageMeta
+		crawler := New(append(options, WithOutput(output))...)
+		crawler.OnFetched(func(request *http.Request, response *Response) {
+			meta = response.Meta
+		})
+
+		assert.NoError(t, crawler.Visit(server.URL+"/"))
+		crawler.Wait()
+
+		// canonical URL of record is the same with and without metadata
+		record := output.records[server.URL+"/"]
+		assert.Equal(t, "Home", record.Title)
+		assert.Equal(t, server.URL+"/home", record.Canonical)
+		if !withMeta {
+			assert.Nil(t, meta)
+			assert.Nil(t, record.Meta)
+			continue
+		}
+		assert.Equal(t, "en", meta.Lang)
+		assert.Equal(t, server.URL+"/home", meta.Canonical)
+		assert.Equal(t, meta, record.Meta)
+	}
+}
@@ -0,0 +1,256 @@
+package crawler
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultPerformanceTop is number of pages in slowest and largest pages rankings.
+const DefaultPerformanceTop = 20
+
+// PerformanceFlag names performance threshold page exceeds.
+type PerformanceFlag string
+
+const (
+	FlagSlowResponse PerformanceFlag = "slow_response"
+	FlagSlowTTFB     PerformanceFlag = "slow_ttfb"
+	FlagLargeHTML    PerformanceFlag = "large_html"
+	FlagHeavyPage    PerformanceFlag = "heavy_page"
+)
+
+// PerformanceThresholds flag pages exceeding them, zero threshold is not checked.
+type PerformanceThresholds struct {
+	ResponseTime time.Duration `json:"response_time"`
+	TTFB         time.Duration `json:"ttfb"`
+	// HTMLSize is size of HTML document in bytes.
+	HTMLSize int `json:"html_size"`
+	// PageWeight is size of HTML document and its resources in bytes.
+	PageWeight int64 `json:"page_weight"`
+}
+
+// DefaultPerformanceThresholds are thresholds used if none are set.
+var DefaultPerformanceThresholds = PerformanceThresholds{
+	ResponseTime: 2 * time.Second,
+	TTFB:         800 * time.Millisecond,
+	HTMLSize:     500 << 10,
+	PageWeight:   2 << 20,
+}
+
+// PagePerformance holds timings and weight of HTML page.
+type PagePerformance struct {
+	URL          string        `json:"url"`
+	ResponseTime time.Duration `json:"response_time"`
+	TTFB         time.Duration `json:"ttfb"`
+	HTMLSize     int           `json:"html_size"`
+	// Resources is number of distinct resources page references, e.g. images, scripts and styles.
+	Resources int `json:"resources"`
+	// ResourcesSize is total size of fetched resources.
+	ResourcesSize int64 `json:"resources_size"`
+	// UnsizedResources is number of resources which were not fetched, so their size is unknown.
+	UnsizedResources int `json:"unsized_resources"`
+	// PageWeight is HTML size plus size of fetched resources.
+	PageWeight int64 `json:"page_weight"`
+	// CacheHit is set if page was served from cache, its timings don't measure fetching page,
+	// so it's not flagged for them and is not ranked among slowest pages.
+	CacheHit bool              `json:"cache_hit,omitempty"`
+	Flags    []PerformanceFlag `json:"flags,omitempty"`
+}
+
+// PerformanceReport holds performance of crawled HTML pages.
+type PerformanceReport struct {
+	Thresholds PerformanceThresholds `json:"thresholds"`
+	// Slowest are pages with the longest response time, slowest first, cache hits are not ranked.
+	Slowest []*PagePerformance `json:"slowest"`
+	// Largest are pages with the largest HTML documents, largest first.
+	Largest []*PagePerformance `json:"largest"`
+	// Pages are sorted by page weight, heaviest first.
+	Pages []*PagePerformance `json:"pages"`
+	// Flagged holds URLs of pages exceeding thresholds by flag.
+	Flagged map[PerformanceFlag][]string `json:"flagged"`
+}
+
+// WriteJSON writes report as JSON document.
+func (r *PerformanceReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// NewPerformanceAudit creates Output collecting page records and resource links to build
+// performance report of successfully fetched HTML pages. Resources are weighted only if they were
+// fetched by the crawl, link tags are resources only if they are stylesheets, preloads or icons.
+// Rankings hold top pages, DefaultPerformanceTop if top is not positive.
+func NewPerformanceAudit(thresholds PerformanceThresholds, top int) *PerformanceAudit {
+	if top <= 0 {
+		top = DefaultPerformanceTop
+	}
+
+	return &PerformanceAudit{
+		thresholds: thresholds,
+		top:        top,
+		resources:  make(map[string]map[string]string),
+	}
+}
+
+// PerformanceAudit is Output building performance report.
+type PerformanceAudit struct {
+	mux        sync.Mutex
+	thresholds PerformanceThresholds
+	top        int
+	records    []*PageRecord
+	// resources holds tag of resources by URL by source page URL
+	resources map[string]map[string]string
+	report    *PerformanceReport
+}
+
+func (a *PerformanceAudit) WritePage(record *PageRecord) error {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	a.records = append(a.records, record)
+	return nil
+}
+
+func (a *PerformanceAudit) usesPageInfo() bool {
+	return false
+}
+
+func (a *PerformanceAudit) WriteLink(link *Link) error {
+	if link.IsRejected() || !resourceTags.Contains(link.Tag) {
+		return nil
+	}
+	// link tags also point to pages and feeds, e.g. canonical or alternate ones
+	if link.Tag == "link" && !hasToken(link.Rel, "stylesheet") && !hasToken(link.Rel, "preload") && !hasToken(link.Rel, "icon") {
+		return nil
+	}
+
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	if _, ok := a.resources[link.Source]; !ok {
+		a.resources[link.Source] = make(map[string]string)
+	}
+	a.resources[link.Source][link.Url.String()] = link.Tag
+	return nil
+}
+
+// Close builds report.
+func (a *PerformanceAudit) Close() error {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	fetched := make(map[string]*PageRecord, len(a.records))
+	for _, record := range a.records {
+		fetched[record.URL] = record
+	}
+
+	report := &PerformanceReport{
+		Thresholds: a.thresholds,
+		Pages:      make([]*PagePerformance, 0),
+		Flagged:    make(map[PerformanceFlag][]string),
+	}
+	for _, record := range a.records {
+		if record.Error != "" || !isHTML(record.ContentType) {
+			continue
+		}
+
+		page := a.page(record, fetched)
+		report.Pages = append(report.Pages, page)
+		for _, flag := range page.Flags {
+			report.Flagged[flag] = append(report.Flagged[flag], page.URL)
+		}
+	}
+	for _, urls := range report.Flagged {
+		sort.Strings(urls)
+	}
+
+	var measured []*PagePerformance
+	for _, page := range report.Pages {
+		if !page.CacheHit {
+			measured = append(measured, page)
+		}
+	}
+	report.Slowest = a.rank(measured, func(p, q *PagePerformance) bool { return p.ResponseTime > q.ResponseTime })
+	report.Largest = a.rank(report.Pages, func(p, q *PagePerformance) bool { return p.HTMLSize > q.HTMLSize })
+	sortPages(report.Pages, func(p, q *PagePerformance) bool { return p.PageWeight > q.PageWeight })
+
+	a.report = report
+	return nil
+}
+
+// Report returns performance report, available after Close.
+func (a *PerformanceAudit) Report() *PerformanceReport {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	return a.report
+}
+
+// page weights page and flags thresholds it exceeds.
+func (a *PerformanceAudit) page(record *PageRecord, fetched map[string]*PageRecord) *PagePerformance {
+	page := &PagePerformance{
+		URL:          record.URL,
+		ResponseTime: record.Duration,
+		HTMLSize:     record.ContentLength,
+		PageWeight:   int64(record.ContentLength),
+		CacheHit:     record.CacheHit,
+	}
+	if record.Timing != nil {
+		page.TTFB = record.Timing.TTFB
+	}
+
+	for url := range a.resources[record.URL] {
+		resource, ok := fetched[url]
+		page.Resources++
+		if !ok || resource.Error != "" {
+			page.UnsizedResources++
+			continue
+		}
+		page.ResourcesSize += int64(resource.ContentLength)
+	}
+	page.PageWeight += page.ResourcesSize
+
+	t := a.thresholds
+	if t.ResponseTime > 0 && page.ResponseTime > t.ResponseTime && !page.CacheHit {
+		page.Flags = append(page.Flags, FlagSlowResponse)
+	}
+	if t.TTFB > 0 && page.TTFB > t.TTFB && !page.CacheHit {
+		page.Flags = append(page.Flags, FlagSlowTTFB)
+	}
+	if t.HTMLSize > 0 && page.HTMLSize > t.HTMLSize {
+		page.Flags = append(page.Flags, FlagLargeHTML)
+	}
+	if t.PageWeight > 0 && page.PageWeight > t.PageWeight {
+		page.Flags = append(page.Flags, FlagHeavyPage)
+	}
+
+	return page
+}
+
+// rank returns top pages ordered by less.
+func (a *PerformanceAudit) rank(pages []*PagePerformance, less func(p, q *PagePerformance) bool) []*PagePerformance {
+	ranked := make([]*PagePerformance, len(pages))
+	copy(ranked, pages)
+	sortPages(ranked, less)
+
+	if len(ranked) > a.top {
+		ranked = ranked[:a.top]
+	}
+	return ranked
+}
+
+// sortPages sorts pages by less, pages are ordered by URL if they are equal.
+func sortPages(pages []*PagePerformance, less func(p, q *PagePerformance) bool) {
+	sort.Slice(pages, func(i, j int) bool {
+		if less(pages[i], pages[j]) {
+			return true
+		}
+		if less(pages[j], pages[i]) {
+			return false
+		}
+		return pages[i].URL < pages[j].URL
+	})
+}
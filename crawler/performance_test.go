@@ -0,0 +1,92 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPerformanceAudit(t *testing.T) {
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<link rel="canonical" href="%[1]s/"><link rel="alternate" hreflang="de" href="%[1]s/de/">
+			<link rel="stylesheet" href="/s.css"><img src="/a.png"><img src="/a.png">
+			<img src="%[1]s/cdn.png"><a href="/slow">slow</a>`, dead.URL)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, strings.Repeat("a", 2000))
+	})
+	mux.HandleFunc("/s.css", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/css")
+		fmt.Fprint(w, strings.Repeat("s", 500))
+	})
+	mux.HandleFunc("/a.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		fmt.Fprint(w, strings.Repeat("p", 1000))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	audit := NewPerformanceAudit(PerformanceThresholds{ResponseTime: 40 * time.Millisecond, HTMLSize: 1000, PageWeight: 1600}, 1)
+	crawler := New(WithOutput(audit))
+	visitAll(crawler)
+	assert.NoError(t, crawler.Run(server.URL+"/"))
+	crawler.Wait()
+	assert.NoError(t, audit.Close())
+
+	report := audit.Report()
+	assert.Equal(t, 2, len(report.Pages))
+
+	// pages are sorted by weight
+	slow, root := report.Pages[0], report.Pages[1]
+	assert.Equal(t, server.URL+"/", root.URL)
+	// canonical and alternate links are pages, image is counted once, image of dead host is unsized
+	assert.Equal(t, 3, root.Resources)
+	assert.Equal(t, 1, root.UnsizedResources)
+	assert.Equal(t, int64(1500), root.ResourcesSize)
+	assert.Equal(t, int64(root.HTMLSize)+1500, root.PageWeight)
+	assert.Equal(t, []PerformanceFlag{FlagHeavyPage}, root.Flags)
+
+	assert.Equal(t, int64(2000), slow.PageWeight)
+	assert.True(t, slow.TTFB >= 50*time.Millisecond)
+	assert.Equal(t, []PerformanceFlag{FlagSlowResponse, FlagLargeHTML, FlagHeavyPage}, slow.Flags)
+
+	assert.Equal(t, []*PagePerformance{slow}, report.Slowest)
+	assert.Equal(t, []*PagePerformance{slow}, report.Largest)
+	assert.Equal(t, map[PerformanceFlag][]string{
+		FlagSlowResponse: {server.URL + "/slow"},
+		FlagLargeHTML:    {server.URL + "/slow"},
+		FlagHeavyPage:    {server.URL + "/", server.URL + "/slow"},
+	}, report.Flagged)
+}
+
+func TestPerformanceAudit_CacheHits(t *testing.T) {
+	audit := NewPerformanceAudit(PerformanceThresholds{ResponseTime: 40 * time.Millisecond, TTFB: 20 * time.Millisecond}, 2)
+	for _, record := range []*PageRecord{
+		{URL: "/fetched", ContentType: "text/html", Duration: 50 * time.Millisecond, Timing: &Timing{TTFB: 30 * time.Millisecond}},
+		{URL: "/cached", ContentType: "text/html", Duration: 60 * time.Millisecond, Timing: &Timing{TTFB: 30 * time.Millisecond}, CacheHit: true},
+	} {
+		assert.NoError(t, audit.WritePage(record))
+	}
+	assert.NoError(t, audit.Close())
+
+	report := audit.Report()
+	assert.Equal(t, 2, len(report.Pages))
+	assert.Equal(t, 1, len(report.Slowest))
+	assert.Equal(t, "/fetched", report.Slowest[0].URL)
+	assert.Equal(t, map[PerformanceFlag][]string{
+		FlagSlowResponse: {"/fetched"},
+		FlagSlowTTFB:     {"/fetched"},
+	}, report.Flagged)
+}
@@ -0,0 +1,212 @@
+package crawler
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestProxy creates HTTP proxy answering requests itself with its name.
+func newTestProxy(name string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", name, r.URL.Host)
+	}))
+}
+
+// newDeadProxy returns URL of proxy refusing connections.
+func newDeadProxy() string {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	return server.URL
+}
+
+func getVia(t *testing.T, client *http.Client, rawURL string) (string, error) {
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return string(b), nil
+}
+
+func proxyClient(t *testing.T, pool *ProxyPool) *http.Client {
+	transport, err := pool.Transport(http.DefaultTransport)
+	assert.NoError(t, err)
+	return &http.Client{Transport: transport}
+}
+
+func TestProxyPool_Rotation(t *testing.T) {
+	p1, p2 := newTestProxy("p1"), newTestProxy("p2")
+	defer p1.Close()
+	defer p2.Close()
+
+	pool, err := NewProxyPool([]string{p1.URL, p2.URL}, RoundRobin, DefaultProxyMaxFailures, DefaultProxyCooldown)
+	assert.NoError(t, err)
+	client := proxyClient(t, pool)
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		body, err := getVia(t, client, "http://a.test/")
+		assert.NoError(t, err)
+		got = append(got, body)
+	}
+	assert.Equal(t, []string{"p1 a.test", "p2 a.test", "p1 a.test", "p2 a.test"}, got)
+
+	pool, err = NewProxyPool([]string{p1.URL, p2.URL}, StickyHost, DefaultProxyMaxFailures, DefaultProxyCooldown)
+	assert.NoError(t, err)
+	client = proxyClient(t, pool)
+
+	got = nil
+	for _, host := range []string{"a.test", "b.test", "a.test", "b.test"} {
+		body, err := getVia(t, client, "http://"+host+"/")
+		assert.NoError(t, err)
+		got = append(got, body)
+	}
+	assert.Equal(t, []string{"p1 a.test", "p2 b.test", "p1 a.test", "p2 b.test"}, got)
+
+	_, err = NewProxyPool([]string{"ftp://10.0.0.1"}, RoundRobin, DefaultProxyMaxFailures, DefaultProxyCooldown)
+	assert.ErrorIs(t, err, ErrUnsupportedProxy)
+}
+
+func TestProxyPool_Unhealthy(t *testing.T) {
+	alive := newTestProxy("alive")
+	defer alive.Close()
+
+	pool, err := NewProxyPool([]string{newDeadProxy(), alive.URL}, RoundRobin, 2, time.Minute)
+	assert.NoError(t, err)
+	now := time.Now()
+	pool.now = func() time.Time { return now }
+	client := proxyClient(t, pool)
+
+	// dead proxy fails twice and is taken out of rotation
+	for i := 0; i < 2; i++ {
+		_, err = getVia(t, client, "http://a.test/")
+		assert.ErrorIs(t, err, ErrProxy)
+		body, err := getVia(t, client, "http://a.test/")
+		assert.NoError(t, err)
+		assert.Equal(t, "alive a.test", body)
+	}
+	for i := 0; i < 2; i++ {
+		body, err := getVia(t, client, "http://a.test/")
+		assert.NoError(t, err)
+		assert.Equal(t, "alive a.test", body)
+	}
+
+	// dead proxy is back in rotation after cooldown
+	now = now.Add(time.Minute)
+	_, err = getVia(t, client, "http://a.test/")
+	assert.ErrorIs(t, err, ErrProxy)
+
+	pool, err = NewProxyPool([]string{newDeadProxy()}, RoundRobin, 1, time.Minute)
+	assert.NoError(t, err)
+	client = proxyClient(t, pool)
+	_, err = getVia(t, client, "http://a.test/")
+	assert.ErrorIs(t, err, ErrProxy)
+	_, err = getVia(t, client, "http://a.test/")
+	assert.ErrorIs(t, err, ErrNoHealthyProxy)
+}
+
+func TestCrawler_WithProxies(t *testing.T) {
+	pool, err := NewProxyPool([]string{newDeadProxy()}, RoundRobin, DefaultProxyMaxFailures, DefaultProxyCooldown)
+	assert.NoError(t, err)
+
+	crawler := New(WithConcurrency(1), WithProxies(pool))
+	assert.NoError(t, crawler.Run("http://a.test/"))
+	crawler.Wait()
+
+	assert.Equal(t, int64(1), crawler.Stat().ProxyErrors())
+	assert.Equal(t, int64(0), crawler.Stat().TotalFetched())
+}
+
+func TestProxyPool_Transport(t *testing.T) {
+	p1 := newTestProxy("p1")
+	defer p1.Close()
+
+	pool, err := NewProxyPool([]string{p1.URL}, RoundRobin, DefaultProxyMaxFailures, DefaultProxyCooldown)
+	assert.NoError(t, err)
+
+	// pool shared by clients keeps transport of each one
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.DisableKeepAlives = true
+	first, err := pool.Transport(base)
+	assert.NoError(t, err)
+	second, err := pool.Transport(http.DefaultTransport)
+	assert.NoError(t, err)
+	assert.True(t, first.(*proxyTransport).transports[0].(*http.Transport).DisableKeepAlives)
+	assert.False(t, second.(*proxyTransport).transports[0].(*http.Transport).DisableKeepAlives)
+
+	_, err = pool.Transport(&errTransport{})
+	assert.ErrorIs(t, err, ErrUnsupportedTransport)
+
+	crawler := New(WithConcurrency(1), WithProxies(pool), WithClient(&http.Client{Transport: &errTransport{}}))
+	assert.NoError(t, crawler.Run("http://a.test/"))
+	crawler.Wait()
+	assert.Equal(t, int64(1), crawler.Stat().ProxyErrors())
+}
+
+// newAuthProxy creates HTTP proxy refusing plain and CONNECT requests with 407.
+func newAuthProxy() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Proxy-Authenticate", `Basic realm="proxy"`)
+		w.WriteHeader(http.StatusProxyAuthRequired)
+	}))
+}
+
+func TestProxyPool_ProxyAuthRequired(t *testing.T) {
+	for _, target := range []string{"http://a.test/", "https://a.test/"} {
+		auth := newAuthProxy()
+
+		pool, err := NewProxyPool([]string{auth.URL}, RoundRobin, 1, time.Minute)
+		assert.NoError(t, err)
+		client := proxyClient(t, pool)
+
+		_, err = getVia(t, client, target)
+		assert.ErrorIs(t, err, ErrProxy, target)
+		_, err = getVia(t, client, target)
+		assert.ErrorIs(t, err, ErrNoHealthyProxy, target)
+
+		auth.Close()
+	}
+}
+
+func TestProxyPool_GatewayError(t *testing.T) {
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer gateway.Close()
+
+	pool, err := NewProxyPool([]string{gateway.URL}, RoundRobin, 1, time.Minute)
+	assert.NoError(t, err)
+	client := proxyClient(t, pool)
+
+	_, err = getVia(t, client, "http://a.test/")
+	assert.ErrorIs(t, err, ErrProxy)
+	_, err = getVia(t, client, "http://a.test/")
+	assert.ErrorIs(t, err, ErrNoHealthyProxy)
+}
+
+func TestProxyPool_TargetTLSError(t *testing.T) {
+	// proxy accepts CONNECT, but answers itself instead of tunnelling to target
+	p1 := newTestProxy("p1")
+	defer p1.Close()
+
+	pool, err := NewProxyPool([]string{p1.URL}, RoundRobin, 1, time.Minute)
+	assert.NoError(t, err)
+	client := proxyClient(t, pool)
+
+	// TLS handshake with target failed, proxy stays in rotation
+	for i := 0; i < 2; i++ {
+		_, err = getVia(t, client, "https://a.test/")
+		assert.Error(t, err)
+		assert.False(t, errors.Is(err, ErrProxy), err)
+	}
+}
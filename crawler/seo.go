@@ -0,0 +1,267 @@
+package crawler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// DefaultMaxTitleLength is title length in characters search engines usually display.
+const DefaultMaxTitleLength = 60
+
+// SEORule names on-page check.
+type SEORule string
+
+const (
+	RuleMissingTitle         SEORule = "missing_title"
+	RuleDuplicateTitle       SEORule = "duplicate_title"
+	RuleTitleTooLong         SEORule = "title_too_long"
+	RuleMissingDescription   SEORule = "missing_description"
+	RuleDuplicateDescription SEORule = "duplicate_description"
+	RuleMissingH1            SEORule = "missing_h1"
+	RuleMultipleH1           SEORule = "multiple_h1"
+	RuleMissingCanonical     SEORule = "missing_canonical"
+	// RuleCanonicalNotOK is canonical URL which was crawled and responded with non-200 status or redirect.
+	RuleCanonicalNotOK  SEORule = "canonical_not_ok"
+	RuleImageMissingAlt SEORule = "image_missing_alt"
+)
+
+// SEOFinding is page failing rule.
+type SEOFinding struct {
+	URL string `json:"url"`
+	// Detail holds value failing rule, e.g. duplicate title, canonical URL or image source.
+	Detail string `json:"detail,omitempty"`
+}
+
+// SEOReport holds findings of on-page audit.
+type SEOReport struct {
+	Pages int `json:"pages"`
+	// Rules holds findings by rule, findings are sorted by URL.
+	Rules map[SEORule][]*SEOFinding `json:"rules"`
+}
+
+// WriteJSON writes report as JSON document.
+func (r *SEOReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// seoPage is audited page with its metadata.
+type seoPage struct {
+	url  string
+	meta *PageMeta
+}
+
+// NewSEOAudit creates on-page audit of fetched HTML pages, titles longer than maxTitleLength
+// characters are reported, DefaultMaxTitleLength is used if it's not positive. Audit is run with WithSEOAudit.
+func NewSEOAudit(maxTitleLength int) *SEOAudit {
+	if maxTitleLength <= 0 {
+		maxTitleLength = DefaultMaxTitleLength
+	}
+
+	return &SEOAudit{
+		maxTitleLength: maxTitleLength,
+		inspected:      make(map[string]struct{}),
+		served:         make(map[string]bool),
+	}
+}
+
+// SEOAudit inspects fetched HTML pages and checks them on Close. It's Output as well, so
+// statuses of crawled canonical URLs are known.
+type SEOAudit struct {
+	mux            sync.Mutex
+	maxTitleLength int
+	pages          []*seoPage
+	inspected      map[string]struct{}
+	// served holds whether crawled URL responded with 200 OK without redirect
+	served map[string]bool
+	report *SEOReport
+}
+
+// Inspect audits fetched HTML page, it's run as crawler OnFetched handler. Response.Meta is used
+// if crawler runs WithPageMeta, otherwise page is parsed.
+func (a *SEOAudit) Inspect(request *http.Request, response *Response) {
+	// redirected page is audited by final URL
+	page := &seoPage{url: request.URL.String(), meta: response.Meta}
+	if response.FinalURL != "" {
+		page.url = response.FinalURL
+	}
+	if page.meta == nil {
+		page.meta = ExtractPageMeta(response)
+	}
+
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	if _, ok := a.inspected[page.url]; ok {
+		return
+	}
+	a.inspected[page.url] = struct{}{}
+	a.pages = append(a.pages, page)
+}
+
+func (a *SEOAudit) WritePage(record *PageRecord) error {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	a.served[record.URL] = record.StatusCode == http.StatusOK && record.RedirectTarget() == ""
+	// final URL of redirect is crawled as well
+	if target := record.RedirectTarget(); target != "" {
+		if _, ok := a.served[target]; !ok {
+			a.served[target] = record.StatusCode == http.StatusOK
+		}
+	}
+	return nil
+}
+
+func (a *SEOAudit) usesPageInfo() bool {
+	return false
+}
+
+func (a *SEOAudit) WriteLink(*Link) error {
+	return nil
+}
+
+// Close checks audited pages.
+func (a *SEOAudit) Close() error {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	report := &SEOReport{Pages: len(a.pages), Rules: make(map[SEORule][]*SEOFinding)}
+	add := func(rule SEORule, url, detail string) {
+		report.Rules[rule] = append(report.Rules[rule], &SEOFinding{URL: url, Detail: detail})
+	}
+
+	titles := make(map[string][]string)
+	descriptions := make(map[string][]string)
+	for _, page := range a.pages {
+		meta := page.meta
+		switch {
+		case meta.Title == "":
+			add(RuleMissingTitle, page.url, "")
+		default:
+			titles[meta.Title] = append(titles[meta.Title], page.url)
+			if utf8.RuneCountInString(meta.Title) > a.maxTitleLength {
+				add(RuleTitleTooLong, page.url, meta.Title)
+			}
+		}
+
+		if meta.Description == "" {
+			add(RuleMissingDescription, page.url, "")
+		} else {
+			descriptions[meta.Description] = append(descriptions[meta.Description], page.url)
+		}
+
+		switch h1 := meta.H1(); {
+		case h1 == 0:
+			add(RuleMissingH1, page.url, "")
+		case h1 > 1:
+			add(RuleMultipleH1, page.url, "")
+		}
+
+		if meta.Canonical == "" {
+			add(RuleMissingCanonical, page.url, "")
+		} else if ok, crawled := a.served[meta.Canonical]; crawled && !ok {
+			add(RuleCanonicalNotOK, page.url, meta.Canonical)
+		}
+
+		for _, src := range meta.ImagesMissingAlt {
+			add(RuleImageMissingAlt, page.url, src)
+		}
+	}
+
+	for title, urls := range titles {
+		if len(urls) > 1 {
+			for _, url := range urls {
+				add(RuleDuplicateTitle, url, title)
+			}
+		}
+	}
+	for description, urls := range descriptions {
+		if len(urls) > 1 {
+			for _, url := range urls {
+				add(RuleDuplicateDescription, url, description)
+			}
+		}
+	}
+
+	for _, findings := range report.Rules {
+		sort.SliceStable(findings, func(i, j int) bool {
+			if findings[i].URL != findings[j].URL {
+				return findings[i].URL < findings[j].URL
+			}
+			return findings[i].Detail < findings[j].Detail
+		})
+	}
+
+	a.report = report
+	return nil
+}
+
+// Report returns audit report, available after Close.
+func (a *SEOAudit) Report() *SEOReport {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	return a.report
+}
+
+func nodeAttr(n *html.Node, key string) (string, bool) {
+	for _, attr := range n.Attr {
+		if attr.Namespace == "" && attr.Key == key {
+			return attr.Val, true
+		}
+	}
+
+	return "", false
+}
+
+func nodeAttrValue(n *html.Node, key string) string {
+	val, _ := nodeAttr(n, key)
+	return val
+}
+
+func nodeText(n *html.Node) string {
+	var b strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.TextNode {
+			b.WriteString(child.Data)
+		}
+	}
+
+	return b.String()
+}
+
+// hasToken checks space separated list, e.g. rel attribute, contains token.
+func hasToken(list, token string) bool {
+	for _, t := range strings.Fields(list) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func normalizeSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// resolveRef resolves reference against page URL, reference is returned as is if it's malformed.
+func resolveRef(base *url.URL, ref string) string {
+	u, err := base.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ref
+	}
+	u.Fragment = ""
+
+	return u.String()
+}
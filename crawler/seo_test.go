@@ -0,0 +1,88 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSEOAudit(t *testing.T) {
+	pages := map[string]string{
+		"/": `<html><head><title>Home</title><meta name="Description" content="home page">
+			<link rel="canonical" href="/"></head>
+			<body><h1>Home</h1><img src="/logo.png" alt=""><a href="/a">a</a><a href="/b">b</a><a href="/old">old</a></body></html>`,
+		"/a": `<html><head><title> Same  title </title><meta name="description" content="same">
+			<link rel="canonical" href="/gone"></head>
+			<body><h1>A</h1><h1>A again</h1><img src="/a.png"><svg><title>icon</title></svg></body></html>`,
+		"/b": `<html><head><title>Same title</title><meta name="description" content="same">
+			<link rel="Canonical nofollow" href="/moved#top"></head><body><img src="/b.png"></body></html>`,
+		"/old": `<html><head><title>This title is much longer than sixty characters, so it's cut off</title></head>
+			<body><h1>Old</h1><a href="/gone">gone</a><a href="/moved">moved</a></body></html>`,
+	}
+	mux := http.NewServeMux()
+	for path, page := range pages {
+		path, page := path, page
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != path {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, page)
+		})
+	}
+	mux.Handle("/gone", http.NotFoundHandler())
+	mux.Handle("/moved", http.RedirectHandler("/", http.StatusMovedPermanently))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	u := func(path string) string {
+		return server.URL + path
+	}
+
+	// audit parses pages itself or uses metadata extracted by crawler
+	for _, options := range [][]Option{nil, {WithPageMeta()}} {
+		audit := NewSEOAudit(0)
+		crawler := New(append(options, WithSEOAudit(audit))...)
+		visitAll(crawler)
+		assert.NoError(t, crawler.Run(server.URL+"/"))
+		crawler.Wait()
+		assert.NoError(t, audit.Close())
+
+		report := audit.Report()
+		assert.Equal(t, 4, report.Pages)
+		assert.Equal(t, map[SEORule][]*SEOFinding{
+			RuleDuplicateTitle:       {{URL: u("/a"), Detail: "Same title"}, {URL: u("/b"), Detail: "Same title"}},
+			RuleTitleTooLong:         {{URL: u("/old"), Detail: "This title is much longer than sixty characters, so it's cut off"}},
+			RuleMissingDescription:   {{URL: u("/old")}},
+			RuleDuplicateDescription: {{URL: u("/a"), Detail: "same"}, {URL: u("/b"), Detail: "same"}},
+			RuleMissingH1:            {{URL: u("/b")}},
+			RuleMultipleH1:           {{URL: u("/a")}},
+			RuleMissingCanonical:     {{URL: u("/old")}},
+			RuleCanonicalNotOK:       {{URL: u("/a"), Detail: u("/gone")}, {URL: u("/b"), Detail: u("/moved")}},
+			RuleImageMissingAlt:      {{URL: u("/a"), Detail: "/a.png"}, {URL: u("/b"), Detail: "/b.png"}},
+		}, report.Rules)
+	}
+}
+
+func TestSEOAudit_PageMeta(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "https://velikodny.com/", nil)
+	audit := NewSEOAudit(0)
+	// extracted metadata is used, so body is not parsed again
+	audit.Inspect(request, &Response{
+		FinalURL: "https://velikodny.com/",
+		Body:     []byte(`<title>Parsed</title>`),
+		Meta: &PageMeta{
+			Title:       "Extracted",
+			Description: "page",
+			Canonical:   "https://velikodny.com/",
+			Headings:    []*Heading{{Level: 1, Text: "Home"}},
+		},
+	})
+	assert.NoError(t, audit.Close())
+
+	assert.Equal(t, map[SEORule][]*SEOFinding{}, audit.Report().Rules)
+}
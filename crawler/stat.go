@@ -1,64 +1,413 @@
 package crawler
 
-import "sync/atomic"
+import (
+	"mime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
 
+// SkipReason names why discovered URL was not fetched.
+type SkipReason string
+
+const (
+	SkipEmptyURL         SkipReason = "empty_url"
+	SkipNotAllowedDomain SkipReason = "not_allowed_domain"
+	SkipAlreadyCrawled   SkipReason = "already_crawled"
+	SkipRejected         SkipReason = "rejected"
+	SkipBudget           SkipReason = "budget"
+	SkipStopped          SkipReason = "stopped"
+)
+
+// Stat counts crawl progress, custom implementation is set with WithStatistic. Crawler counts
+// detailed statistic, e.g. errors, responses and queue, on its own, unless Stat implements DetailedStat.
 type Stat interface {
 	AddPage()
-	PagesCount() int32
+	PagesCount() int64
 	AddTotalDiscovered()
-	TotalDiscovered() int32
+	TotalDiscovered() int64
 	AddUniqDiscovered()
-	UniqDiscovered() int32
+	UniqDiscovered() int64
 	AddTotalFetched()
-	TotalFetched() int32
+	TotalFetched() int64
+}
+
+// DetailedStat is Stat counting detailed statistic as well, NewStat implements it.
+type DetailedStat interface {
+	Stat
+	AddCacheHit()
+	CacheHits() int64
+	AddCacheMiss()
+	CacheMisses() int64
+	AddProxyError()
+	ProxyErrors() int64
+	SetStopReason(reason string)
+	StopReason() string
+	AddFetchError(phase FetchPhase)
+	FetchErrors() map[FetchPhase]int64
+	// AddResponse counts received response by status and content type, its size and response time of host.
+	AddResponse(host string, statusCode int, contentType string, size int, duration time.Duration)
+	// AddTiming counts request phases durations, phases which didn't happen are not counted.
+	AddTiming(timing Timing)
+	AddRedirects(n int)
+	AddRetry()
+	AddSkipped(reason SkipReason)
+	// AddQueued and AddInFlight change number of URLs waiting for fetcher and being fetched.
+	AddQueued(delta int)
+	AddInFlight(delta int)
+	Snapshot() StatSnapshot
+}
+
+// detailed returns stat counting detailed statistic. Stat which doesn't implement DetailedStat
+// counts its own counters, detailed statistic is counted in memory.
+func detailed(stat Stat) DetailedStat {
+	if d, ok := stat.(DetailedStat); ok {
+		return d
+	}
+
+	return &detailedStat{stat: stat, DetailedStat: NewStat()}
+}
+
+// detailedStat combines custom Stat with in-memory detailed statistic.
+type detailedStat struct {
+	stat Stat
+	DetailedStat
+}
+
+func (s *detailedStat) AddPage()               { s.stat.AddPage() }
+func (s *detailedStat) PagesCount() int64      { return s.stat.PagesCount() }
+func (s *detailedStat) AddTotalDiscovered()    { s.stat.AddTotalDiscovered() }
+func (s *detailedStat) TotalDiscovered() int64 { return s.stat.TotalDiscovered() }
+func (s *detailedStat) AddUniqDiscovered()     { s.stat.AddUniqDiscovered() }
+func (s *detailedStat) UniqDiscovered() int64  { return s.stat.UniqDiscovered() }
+func (s *detailedStat) AddTotalFetched()       { s.stat.AddTotalFetched() }
+func (s *detailedStat) TotalFetched() int64    { return s.stat.TotalFetched() }
+
+func (s *detailedStat) Snapshot() StatSnapshot {
+	snapshot := s.DetailedStat.Snapshot()
+	snapshot.Pages = s.stat.PagesCount()
+	snapshot.TotalDiscovered = s.stat.TotalDiscovered()
+	snapshot.UniqDiscovered = s.stat.UniqDiscovered()
+	snapshot.TotalFetched = s.stat.TotalFetched()
+
+	return snapshot
 }
 
 type PublicStat interface {
-	PagesCount() int32
-	TotalDiscovered() int32
-	UniqDiscovered() int32
-	TotalFetched() int32
+	// PagesCount is count of fetched HTML pages.
+	PagesCount() int64
+	TotalDiscovered() int64
+	UniqDiscovered() int64
+	TotalFetched() int64
+	CacheHits() int64
+	CacheMisses() int64
+	ProxyErrors() int64
+	// StopReason is budget which stopped crawl, empty if crawl was not stopped.
+	StopReason() string
+	// FetchErrors are counts of failed fetches by phase.
+	FetchErrors() map[FetchPhase]int64
+	// Snapshot returns copy of all counters.
+	Snapshot() StatSnapshot
+}
+
+// StatSnapshot is serialisable copy of crawler statistic.
+type StatSnapshot struct {
+	Pages           int64 `json:"pages"`
+	TotalDiscovered int64 `json:"discovered"`
+	UniqDiscovered  int64 `json:"unique"`
+	TotalFetched    int64 `json:"fetched"`
+	// Bytes is total size of received response bodies.
+	Bytes       int64  `json:"bytes"`
+	Redirects   int64  `json:"redirects"`
+	Retries     int64  `json:"retries"`
+	CacheHits   int64  `json:"cache_hits"`
+	CacheMisses int64  `json:"cache_misses"`
+	ProxyErrors int64  `json:"proxy_errors"`
+	StopReason  string `json:"stop_reason,omitempty"`
+	Queued      int64  `json:"queued"`
+	InFlight    int64  `json:"in_flight"`
+
+	StatusCodes  map[int]int64        `json:"status_codes"`
+	ContentTypes map[string]int64     `json:"content_types"`
+	Errors       map[FetchPhase]int64 `json:"errors"`
+	Skipped      map[SkipReason]int64 `json:"skipped"`
+	// Latency is histogram of response times.
+	Latency HistogramSnapshot `json:"latency"`
+	// HostLatency are histograms of response times by host.
+	HostLatency map[string]HistogramSnapshot `json:"host_latency"`
+	// Timing are histograms of request phases durations.
+	Timing TimingSnapshot `json:"timing"`
+}
+
+// TimingSnapshot holds histograms of request phases durations, see Timing.
+type TimingSnapshot struct {
+	DNS      HistogramSnapshot `json:"dns"`
+	Connect  HistogramSnapshot `json:"connect"`
+	TLS      HistogramSnapshot `json:"tls"`
+	TTFB     HistogramSnapshot `json:"ttfb"`
+	Transfer HistogramSnapshot `json:"transfer"`
+	// ConnReused is count of requests sent over kept-alive connections.
+	ConnReused int64 `json:"conn_reused"`
 }
 
 type inMemStat struct {
-	pagesCount      int32
-	totalDiscovered int32
-	uniqDiscovered  int32
-	totalFetched    int32
+	pagesCount      int64
+	totalDiscovered int64
+	uniqDiscovered  int64
+	totalFetched    int64
+	cacheHits       int64
+	cacheMisses     int64
+	proxyErrors     int64
+	bytes           int64
+	redirects       int64
+	retries         int64
+	queued          int64
+	inFlight        int64
+	connReused      int64
+
+	latency  *histogram
+	dns      *histogram
+	connect  *histogram
+	tls      *histogram
+	ttfb     *histogram
+	transfer *histogram
+
+	mux          sync.Mutex
+	stopReason   string
+	fetchErrors  map[FetchPhase]int64
+	statusCodes  map[int]int64
+	contentTypes map[string]int64
+	skipped      map[SkipReason]int64
+	hostLatency  map[string]*histogram
 }
 
-func NewStat() Stat {
-	return &inMemStat{}
+// NewStat creates in-memory statistic.
+func NewStat() DetailedStat {
+	return &inMemStat{
+		latency:      newHistogram(latencyBuckets),
+		dns:          newHistogram(latencyBuckets),
+		connect:      newHistogram(latencyBuckets),
+		tls:          newHistogram(latencyBuckets),
+		ttfb:         newHistogram(latencyBuckets),
+		transfer:     newHistogram(latencyBuckets),
+		fetchErrors:  make(map[FetchPhase]int64),
+		statusCodes:  make(map[int]int64),
+		contentTypes: make(map[string]int64),
+		skipped:      make(map[SkipReason]int64),
+		hostLatency:  make(map[string]*histogram),
+	}
 }
 
 func (s *inMemStat) AddPage() {
-	atomic.AddInt32(&s.pagesCount, 1)
+	atomic.AddInt64(&s.pagesCount, 1)
 }
 
-func (s *inMemStat) PagesCount() int32 {
-	return atomic.LoadInt32(&s.pagesCount)
+func (s *inMemStat) PagesCount() int64 {
+	return atomic.LoadInt64(&s.pagesCount)
 }
 
 func (s *inMemStat) AddTotalDiscovered() {
-	atomic.AddInt32(&s.totalDiscovered, 1)
+	atomic.AddInt64(&s.totalDiscovered, 1)
 }
 
-func (s *inMemStat) TotalDiscovered() int32 {
-	return atomic.LoadInt32(&s.totalDiscovered)
+func (s *inMemStat) TotalDiscovered() int64 {
+	return atomic.LoadInt64(&s.totalDiscovered)
 }
 
 func (s *inMemStat) AddUniqDiscovered() {
-	atomic.AddInt32(&s.uniqDiscovered, 1)
+	atomic.AddInt64(&s.uniqDiscovered, 1)
 }
 
-func (s *inMemStat) UniqDiscovered() int32 {
-	return atomic.LoadInt32(&s.uniqDiscovered)
+func (s *inMemStat) UniqDiscovered() int64 {
+	return atomic.LoadInt64(&s.uniqDiscovered)
 }
 
 func (s *inMemStat) AddTotalFetched() {
-	atomic.AddInt32(&s.totalFetched, 1)
+	atomic.AddInt64(&s.totalFetched, 1)
+}
+
+func (s *inMemStat) TotalFetched() int64 {
+	return atomic.LoadInt64(&s.totalFetched)
+}
+
+func (s *inMemStat) AddCacheHit() {
+	atomic.AddInt64(&s.cacheHits, 1)
+}
+
+func (s *inMemStat) CacheHits() int64 {
+	return atomic.LoadInt64(&s.cacheHits)
+}
+
+func (s *inMemStat) AddCacheMiss() {
+	atomic.AddInt64(&s.cacheMisses, 1)
+}
+
+func (s *inMemStat) CacheMisses() int64 {
+	return atomic.LoadInt64(&s.cacheMisses)
+}
+
+func (s *inMemStat) AddProxyError() {
+	atomic.AddInt64(&s.proxyErrors, 1)
+}
+
+func (s *inMemStat) ProxyErrors() int64 {
+	return atomic.LoadInt64(&s.proxyErrors)
 }
 
-func (s *inMemStat) TotalFetched() int32 {
-	return atomic.LoadInt32(&s.totalFetched)
+// SetStopReason records the first reason crawl was stopped.
+func (s *inMemStat) SetStopReason(reason string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.stopReason == "" {
+		s.stopReason = reason
+	}
+}
+
+func (s *inMemStat) StopReason() string {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	return s.stopReason
+}
+
+func (s *inMemStat) AddFetchError(phase FetchPhase) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.fetchErrors[phase]++
+}
+
+// FetchErrors returns copy of counts.
+func (s *inMemStat) FetchErrors() map[FetchPhase]int64 {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	errors := make(map[FetchPhase]int64, len(s.fetchErrors))
+	for phase, n := range s.fetchErrors {
+		errors[phase] = n
+	}
+	return errors
+}
+
+func (s *inMemStat) AddResponse(host string, statusCode int, contentType string, size int, duration time.Duration) {
+	atomic.AddInt64(&s.bytes, int64(size))
+	s.latency.observe(duration)
+
+	s.mux.Lock()
+	s.statusCodes[statusCode]++
+	s.contentTypes[mediaType(contentType)]++
+	hostLatency, ok := s.hostLatency[host]
+	if !ok {
+		hostLatency = newHistogram(latencyBuckets)
+		s.hostLatency[host] = hostLatency
+	}
+	s.mux.Unlock()
+
+	hostLatency.observe(duration)
+}
+
+func (s *inMemStat) AddTiming(timing Timing) {
+	if timing.DNS > 0 {
+		s.dns.observe(timing.DNS)
+	}
+	if timing.Connect > 0 {
+		s.connect.observe(timing.Connect)
+	}
+	if timing.TLS > 0 {
+		s.tls.observe(timing.TLS)
+	}
+	// no first byte is received for cache hits and failed requests
+	if timing.TTFB > 0 {
+		s.ttfb.observe(timing.TTFB)
+		s.transfer.observe(timing.Transfer)
+	}
+	if timing.ConnReused {
+		atomic.AddInt64(&s.connReused, 1)
+	}
+}
+
+func (s *inMemStat) AddRedirects(n int) {
+	atomic.AddInt64(&s.redirects, int64(n))
+}
+
+func (s *inMemStat) AddRetry() {
+	atomic.AddInt64(&s.retries, 1)
+}
+
+func (s *inMemStat) AddSkipped(reason SkipReason) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.skipped[reason]++
+}
+
+func (s *inMemStat) AddQueued(delta int) {
+	atomic.AddInt64(&s.queued, int64(delta))
+}
+
+func (s *inMemStat) AddInFlight(delta int) {
+	atomic.AddInt64(&s.inFlight, int64(delta))
+}
+
+func (s *inMemStat) Snapshot() StatSnapshot {
+	snapshot := StatSnapshot{
+		Pages:           s.PagesCount(),
+		TotalDiscovered: s.TotalDiscovered(),
+		UniqDiscovered:  s.UniqDiscovered(),
+		TotalFetched:    s.TotalFetched(),
+		Bytes:           atomic.LoadInt64(&s.bytes),
+		Redirects:       atomic.LoadInt64(&s.redirects),
+		Retries:         atomic.LoadInt64(&s.retries),
+		CacheHits:       s.CacheHits(),
+		CacheMisses:     s.CacheMisses(),
+		ProxyErrors:     s.ProxyErrors(),
+		Queued:          atomic.LoadInt64(&s.queued),
+		InFlight:        atomic.LoadInt64(&s.inFlight),
+		Errors:          s.FetchErrors(),
+		Latency:         s.latency.snapshot(),
+		Timing: TimingSnapshot{
+			DNS:        s.dns.snapshot(),
+			Connect:    s.connect.snapshot(),
+			TLS:        s.tls.snapshot(),
+			TTFB:       s.ttfb.snapshot(),
+			Transfer:   s.transfer.snapshot(),
+			ConnReused: atomic.LoadInt64(&s.connReused),
+		},
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	snapshot.StopReason = s.stopReason
+	snapshot.StatusCodes = make(map[int]int64, len(s.statusCodes))
+	for code, n := range s.statusCodes {
+		snapshot.StatusCodes[code] = n
+	}
+	snapshot.ContentTypes = make(map[string]int64, len(s.contentTypes))
+	for contentType, n := range s.contentTypes {
+		snapshot.ContentTypes[contentType] = n
+	}
+	snapshot.Skipped = make(map[SkipReason]int64, len(s.skipped))
+	for reason, n := range s.skipped {
+		snapshot.Skipped[reason] = n
+	}
+	snapshot.HostLatency = make(map[string]HistogramSnapshot, len(s.hostLatency))
+	for host, h := range s.hostLatency {
+		snapshot.HostLatency[host] = h.snapshot()
+	}
+	return snapshot
+}
+
+// mediaType returns content type without parameters, "unknown" if it's not set.
+func mediaType(contentType string) string {
+	if contentType == "" {
+		return "unknown"
+	}
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mediaType
+	}
+
+	return strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
 }
@@ -0,0 +1,122 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistogram(t *testing.T) {
+	h := newHistogram([]time.Duration{10 * time.Millisecond, 100 * time.Millisecond})
+	for _, d := range []time.Duration{5 * time.Millisecond, 5 * time.Millisecond, 50 * time.Millisecond, 50 * time.Millisecond, time.Second} {
+		h.observe(d)
+	}
+
+	s := h.snapshot()
+	assert.Equal(t, []HistogramBucket{{LE: 10 * time.Millisecond, Count: 2}, {LE: 100 * time.Millisecond, Count: 4}}, s.Buckets)
+	assert.Equal(t, int64(5), s.Count)
+	assert.Equal(t, 222*time.Millisecond, s.Mean())
+	assert.Equal(t, 5*time.Millisecond, s.Quantile(0.2))
+	assert.Equal(t, 32500*time.Microsecond, s.P50)
+	// quantiles above the last bound are the max observation
+	assert.Equal(t, time.Second, s.P99)
+
+	assert.Equal(t, time.Duration(0), newHistogram(latencyBuckets).snapshot().P50)
+}
+
+func TestStat_AddTiming(t *testing.T) {
+	stat := NewStat()
+	stat.AddTiming(Timing{TTFB: 20 * time.Millisecond, Transfer: 5 * time.Millisecond, ConnReused: true})
+	// phases of request without connection, e.g. cache hit, are not counted
+	stat.AddTiming(Timing{})
+
+	s := stat.Snapshot().Timing
+	assert.Equal(t, int64(0), s.DNS.Count)
+	assert.Equal(t, int64(1), s.TTFB.Count)
+	assert.Equal(t, int64(1), s.Transfer.Count)
+	assert.Equal(t, int64(1), s.ConnReused)
+}
+
+func TestMediaType(t *testing.T) {
+	assert.Equal(t, "text/html", mediaType("text/html; charset=utf-8"))
+	assert.Equal(t, "text/html", mediaType("Text/HTML"))
+	assert.Equal(t, "unknown", mediaType(""))
+}
+
+func TestCrawler_Stat(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<a href="/old">old</a><a href="/">root</a><a href="/missing">missing</a>`)
+	})
+	mux.Handle("/old", http.RedirectHandler("/new", http.StatusMovedPermanently))
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `new`)
+	})
+	mux.HandleFunc("/missing", http.NotFound)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	crawler := New(WithConcurrency(1))
+	visitAll(crawler)
+
+	assert.NoError(t, crawler.Run(server.URL+"/"))
+	crawler.Wait()
+	assert.Error(t, crawler.Visit(""))
+
+	s := crawler.Stat().Snapshot()
+	assert.Equal(t, int64(2), s.Pages)
+	assert.Equal(t, int64(5), s.TotalDiscovered)
+	assert.Equal(t, int64(3), s.UniqDiscovered)
+	assert.Equal(t, int64(2), s.TotalFetched)
+	assert.Equal(t, int64(1), s.Redirects)
+	assert.Equal(t, map[int]int64{200: 2, 404: 1}, s.StatusCodes)
+	assert.Equal(t, map[string]int64{"text/html": 2, "text/plain": 1}, s.ContentTypes)
+	assert.Equal(t, map[FetchPhase]int64{PhaseHTTPStatus: 1}, s.Errors)
+	assert.Equal(t, map[SkipReason]int64{SkipAlreadyCrawled: 1, SkipEmptyURL: 1}, s.Skipped)
+	assert.Equal(t, int64(3), s.Latency.Count)
+	assert.True(t, s.Bytes > 0)
+}
+
+// countingStat is custom Stat counting pages only.
+type countingStat struct {
+	pages int64
+}
+
+func (s *countingStat) AddPage()               { atomic.AddInt64(&s.pages, 1) }
+func (s *countingStat) PagesCount() int64      { return atomic.LoadInt64(&s.pages) }
+func (s *countingStat) AddTotalDiscovered()    {}
+func (s *countingStat) TotalDiscovered() int64 { return 0 }
+func (s *countingStat) AddUniqDiscovered()     {}
+func (s *countingStat) UniqDiscovered() int64  { return 0 }
+func (s *countingStat) AddTotalFetched()       {}
+func (s *countingStat) TotalFetched() int64    { return 0 }
+
+func TestCrawler_WithStatistic(t *testing.T) {
+	server := newTestSite()
+	defer server.Close()
+
+	stat := &countingStat{}
+	crawler := New(WithConcurrency(1), WithStatistic(stat))
+	visitAll(crawler)
+
+	assert.NoError(t, crawler.Run(server.URL+"/"))
+	crawler.Wait()
+
+	// custom counters are used, detailed statistic is counted by crawler
+	s := crawler.Stat().Snapshot()
+	assert.Equal(t, int64(2), stat.PagesCount())
+	assert.Equal(t, int64(2), s.Pages)
+	assert.Equal(t, int64(0), s.TotalFetched)
+	assert.Equal(t, map[int]int64{200: 3, 404: 1}, s.StatusCodes)
+
+	// detailed statistic is used as is
+	detailedStat := NewStat()
+	assert.Equal(t, detailedStat, detailed(detailedStat))
+}
@@ -0,0 +1,70 @@
+package crawler
+
+import (
+	"bytes"
+	"strings"
+
+	. "github.com/deckarep/golang-set"
+	"golang.org/x/net/html"
+)
+
+var (
+	// hiddenTags content is never rendered
+	hiddenTags = NewSet("head", "script", "style", "noscript", "template", "svg")
+	// blockTags start new line of text
+	blockTags = NewSet("p", "div", "br", "hr", "li", "ul", "ol", "dl", "dt", "dd", "tr", "table", "h1", "h2", "h3", "h4", "h5", "h6",
+		"section", "article", "header", "footer", "nav", "aside", "main", "blockquote", "pre", "form", "figure", "figcaption", "title")
+)
+
+// VisibleText extracts text of HTML page as it would be read, one line per block element.
+// Page title is kept as the first line.
+func VisibleText(body []byte) string {
+	var lines []string
+	var line strings.Builder
+	hidden := 0
+	inTitle := false
+
+	flush := func() {
+		if text := strings.Join(strings.Fields(line.String()), " "); text != "" {
+			lines = append(lines, text)
+		}
+		line.Reset()
+	}
+
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		tt := z.Next()
+
+		switch tt {
+		case html.ErrorToken:
+			flush()
+			return strings.Join(lines, "\n")
+		case html.TextToken:
+			if hidden == 0 || inTitle {
+				line.Write(z.Text())
+				line.WriteByte(' ')
+			}
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+
+			if blockTags.Contains(tag) {
+				flush()
+			}
+			if tag == "body" {
+				// closing head tag is optional
+				hidden = 0
+			}
+			if tag == "title" {
+				inTitle = tt == html.StartTagToken
+			}
+			if tt != html.SelfClosingTagToken && hiddenTags.Contains(tag) {
+				if tt == html.StartTagToken {
+					hidden++
+				} else if hidden > 0 {
+					hidden--
+				}
+			}
+		}
+	}
+}
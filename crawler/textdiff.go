@@ -0,0 +1,104 @@
+package crawler
+
+import (
+	"html/template"
+	"io"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// TextDiff holds unified diff of visible text of changed page.
+type TextDiff struct {
+	URL  string
+	Diff string
+}
+
+// DiffCrawls returns unified diffs of visible text of pages changed between crawls.
+// Only pages crawled with text, see WithPageText, can be compared.
+func DiffCrawls(previous, current []*PageRecord) ([]*TextDiff, error) {
+	before := pagesByURL(previous)
+	after := pagesByURL(current)
+
+	diffs := make([]*TextDiff, 0)
+	for _, change := range CompareCrawls(previous, current).Pages {
+		if change.Status != PageChanged {
+			continue
+		}
+
+		old, record := before[change.URL], after[change.URL]
+		if old.Text == record.Text {
+			continue
+		}
+
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(old.Text),
+			B:        difflib.SplitLines(record.Text),
+			FromFile: change.URL + " " + old.FetchedAt.Format("2006-01-02 15:04:05"),
+			ToFile:   change.URL + " " + record.FetchedAt.Format("2006-01-02 15:04:05"),
+			Context:  3,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		diffs = append(diffs, &TextDiff{URL: change.URL, Diff: diff})
+	}
+
+	return diffs, nil
+}
+
+// WriteDiffs writes diffs as plain text.
+func WriteDiffs(w io.Writer, diffs []*TextDiff) error {
+	for _, diff := range diffs {
+		if _, err := io.WriteString(w, diff.Diff); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var diffTemplate = template.Must(template.New("diff").Funcs(template.FuncMap{
+	"lines": func(diff string) []string {
+		return strings.Split(strings.TrimSuffix(diff, "\n"), "\n")
+	},
+	"class": func(line string) string {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			return "file"
+		case strings.HasPrefix(line, "@@"):
+			return "hunk"
+		case strings.HasPrefix(line, "+"):
+			return "added"
+		case strings.HasPrefix(line, "-"):
+			return "removed"
+		}
+		return ""
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Changed pages</title>
+<style>
+body { font-family: sans-serif; }
+pre { background: #f6f8fa; padding: 8px; white-space: pre-wrap; }
+.file { font-weight: bold; }
+.hunk { color: #6f42c1; }
+.added { background: #e6ffed; }
+.removed { background: #ffeef0; }
+</style>
+</head>
+<body>
+<h1>Changed pages: {{len .}}</h1>
+{{range .}}<h2><a href="{{.URL}}">{{.URL}}</a></h2>
+<pre>{{range lines .Diff}}<div class="{{class .}}">{{.}}</div>{{end}}</pre>
+{{end}}</body>
+</html>
+`))
+
+// WriteDiffsHTML writes diffs as HTML report.
+func WriteDiffsHTML(w io.Writer, diffs []*TextDiff) error {
+	return diffTemplate.Execute(w, diffs)
+}
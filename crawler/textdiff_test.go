@@ -0,0 +1,41 @@
+package crawler
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVisibleText(t *testing.T) {
+	text := VisibleText([]byte(`<html><head><title>Title</title><style>p { color: red; }</style>
+		<script>var a = "<p>";</script></head>
+		<body><h1>Header</h1><p>First   <b>paragraph</b>
+		text</p><div>Second<br>line</div><noscript>enable js</noscript></body></html>`))
+
+	assert.Equal(t, "Title\nHeader\nFirst paragraph text\nSecond\nline", text)
+}
+
+func TestDiffCrawls(t *testing.T) {
+	previous := []*PageRecord{
+		{URL: "/", ContentMd5: "1", Text: "Title\nfirst\nsecond"},
+		{URL: "/same", ContentMd5: "2", Text: "same"},
+		// markup changed, text didn't
+		{URL: "/markup", ContentMd5: "3", Text: "markup"},
+	}
+	current := []*PageRecord{
+		{URL: "/", ContentMd5: "11", Text: "Title\nfirst\nchanged"},
+		{URL: "/same", ContentMd5: "2", Text: "same"},
+		{URL: "/markup", ContentMd5: "33", Text: "markup"},
+	}
+
+	diffs, err := DiffCrawls(previous, current)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(diffs))
+	assert.Equal(t, "/", diffs[0].URL)
+	assert.Contains(t, diffs[0].Diff, "@@ -1,3 +1,3 @@\n Title\n first\n-second\n+changed\n")
+
+	var b bytes.Buffer
+	assert.NoError(t, WriteDiffsHTML(&b, diffs))
+	assert.Contains(t, b.String(), `<div class="removed">-second</div><div class="added">&#43;changed</div>`)
+}
@@ -0,0 +1,123 @@
+package crawler
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timing is breakdown of request duration. If redirects were followed or request was retried,
+// timing is of the last request. Phases which didn't happen, e.g. DNS lookup and connect
+// for reused connection, are zero.
+type Timing struct {
+	DNS     time.Duration `json:"dns"`
+	Connect time.Duration `json:"connect"`
+	TLS     time.Duration `json:"tls"`
+	// TTFB is time from request written to the first response byte, i.e. server think time.
+	TTFB time.Duration `json:"ttfb"`
+	// Transfer is time from the first response byte to body read.
+	Transfer time.Duration `json:"transfer"`
+	// ConnReused is set if request was sent over kept-alive connection.
+	ConnReused bool `json:"conn_reused"`
+}
+
+// requestTrace collects timing of request with httptrace, hooks may be called from different goroutines.
+type requestTrace struct {
+	mux          sync.Mutex
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	gotConn      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+	timing       Timing
+}
+
+func (t *requestTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		// every request of redirect chain gets connection, so the last request is traced
+		GetConn: func(string) {
+			t.lock(t.reset)
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.lock(func() { t.dnsStart = time.Now() })
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.lock(func() { t.timing.DNS = since(t.dnsStart) })
+		},
+		ConnectStart: func(string, string) {
+			t.lock(func() {
+				// the first dial of parallel dials to several addresses is measured
+				if t.connectStart.IsZero() {
+					t.connectStart = time.Now()
+				}
+			})
+		},
+		ConnectDone: func(_, _ string, err error) {
+			t.lock(func() {
+				if err == nil {
+					t.timing.Connect = since(t.connectStart)
+				}
+			})
+		},
+		TLSHandshakeStart: func() {
+			t.lock(func() { t.tlsStart = time.Now() })
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.lock(func() { t.timing.TLS = since(t.tlsStart) })
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.lock(func() {
+				t.gotConn = time.Now()
+				t.timing.ConnReused = info.Reused
+			})
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.lock(func() { t.wroteRequest = time.Now() })
+		},
+		GotFirstResponseByte: func() {
+			t.lock(func() { t.firstByte = time.Now() })
+		},
+	}
+}
+
+func (t *requestTrace) lock(f func()) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	f()
+}
+
+func (t *requestTrace) reset() {
+	t.dnsStart, t.connectStart, t.tlsStart = time.Time{}, time.Time{}, time.Time{}
+	t.gotConn, t.wroteRequest, t.firstByte = time.Time{}, time.Time{}, time.Time{}
+	t.timing = Timing{}
+}
+
+// done returns timing of request which body was read at bodyRead.
+func (t *requestTrace) done(bodyRead time.Time) Timing {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	timing := t.timing
+	if !t.firstByte.IsZero() {
+		sent := t.wroteRequest
+		if sent.IsZero() {
+			sent = t.gotConn
+		}
+		if !sent.IsZero() {
+			timing.TTFB = t.firstByte.Sub(sent)
+		}
+		timing.Transfer = bodyRead.Sub(t.firstByte)
+	}
+
+	return timing
+}
+
+func since(start time.Time) time.Duration {
+	if start.IsZero() {
+		return 0
+	}
+
+	return time.Since(start)
+}
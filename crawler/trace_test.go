@@ -0,0 +1,61 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCrawler_Timing(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "page")
+	}))
+	defer server.Close()
+
+	output := &recordsOutput{records: make(map[string]*PageRecord)}
+	crawler := New(WithConcurrency(1), WithOutput(output), WithClient(server.Client()))
+
+	assert.NoError(t, crawler.Visit(server.URL+"/"))
+	crawler.Wait()
+	assert.NoError(t, crawler.Visit(server.URL+"/next"))
+	crawler.Wait()
+
+	first := output.records[server.URL+"/"].Timing
+	assert.False(t, first.ConnReused)
+	assert.True(t, first.Connect > 0)
+	assert.True(t, first.TLS > 0)
+	assert.True(t, first.TTFB >= 20*time.Millisecond)
+	// IP address is not resolved
+	assert.Equal(t, time.Duration(0), first.DNS)
+
+	next := output.records[server.URL+"/next"].Timing
+	assert.True(t, next.ConnReused)
+	assert.Equal(t, time.Duration(0), next.Connect)
+	assert.Equal(t, time.Duration(0), next.TLS)
+	assert.True(t, next.TTFB >= 20*time.Millisecond)
+
+	s := crawler.Stat().Snapshot()
+	assert.Equal(t, int64(1), s.Timing.ConnReused)
+	assert.Equal(t, int64(1), s.Timing.Connect.Count)
+	assert.Equal(t, int64(1), s.Timing.TLS.Count)
+	assert.Equal(t, int64(2), s.Timing.TTFB.Count)
+	assert.Equal(t, int64(0), s.Timing.DNS.Count)
+}
+
+func TestCrawler_TimingFailed(t *testing.T) {
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	output := &recordsOutput{records: make(map[string]*PageRecord)}
+	crawler := New(WithOutput(output))
+	assert.NoError(t, crawler.Visit(dead.URL+"/"))
+	crawler.Wait()
+
+	assert.Nil(t, output.records[dead.URL+"/"].Timing)
+}
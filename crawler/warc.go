@@ -0,0 +1,216 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	warcVersion    = "WARC/1.1"
+	warcDateFormat = "2006-01-02T15:04:05Z"
+	// DefaultWARCSize is the size WARC files roll over at if size is not set.
+	DefaultWARCSize = 1 << 30
+)
+
+// WARCWriter archives fetched resources as WARC/1.1 request and response records.
+// Every record is compressed as a separate gzip member, so files can be read by
+// standard tools as well as by random access. A new file is started when the
+// current one exceeds configured size.
+type WARCWriter struct {
+	mux sync.Mutex
+	// dir holds WARC files
+	dir string
+	// prefix of WARC file names
+	prefix string
+	// maxSize of WARC file in bytes
+	maxSize int64
+	// serial number of current file
+	serial int
+	file   *os.File
+	// size of current file
+	size int64
+}
+
+// NewWARCWriter creates WARCWriter writing files named <prefix>-<timestamp>-<serial>.warc.gz to dir.
+func NewWARCWriter(dir, prefix string, maxSize int64) (*WARCWriter, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultWARCSize
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &WARCWriter{
+		dir:     dir,
+		prefix:  prefix,
+		maxSize: maxSize,
+	}, nil
+}
+
+// warcRedactedHeaders hold credentials of request, their values are not archived.
+var warcRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+// WriteExchange writes request and response records of fetched resource, preceded by records
+// of redirects followed to fetch it. Body holds response body as decoded by transport,
+// bodies of redirect responses are discarded by http.Client, so their records hold headers only.
+// Credentials sent with requests are redacted.
+func (w *WARCWriter) WriteExchange(resp *http.Response, body []byte) error {
+	var redirects []*http.Response
+	for r := resp.Request.Response; r != nil; r = r.Request.Response {
+		redirects = append([]*http.Response{r}, redirects...)
+	}
+
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	for _, redirect := range redirects {
+		if err := w.writeExchange(redirect, nil); err != nil {
+			return err
+		}
+	}
+
+	return w.writeExchange(resp, body)
+}
+
+// writeExchange writes request and response records of a single exchange, must be called with mux locked.
+func (w *WARCWriter) writeExchange(resp *http.Response, body []byte) error {
+	if w.file == nil || w.size >= w.maxSize {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+
+	request := resp.Request
+	date := time.Now().UTC().Format(warcDateFormat)
+	targetURI := request.URL.String()
+
+	var responseBlock bytes.Buffer
+	fmt.Fprintf(&responseBlock, "%s %s\r\n", resp.Proto, resp.Status)
+	resp.Header.Write(&responseBlock)
+	responseBlock.WriteString("\r\n")
+	responseBlock.Write(body)
+
+	responseID := warcRecordID()
+	if err := w.writeRecord([][2]string{
+		{"WARC-Type", "response"},
+		{"WARC-Record-ID", responseID},
+		{"WARC-Date", date},
+		{"WARC-Target-URI", targetURI},
+		{"Content-Type", "application/http;msgtype=response"},
+		{"WARC-Payload-Digest", warcDigest(body)},
+	}, responseBlock.Bytes()); err != nil {
+		return err
+	}
+
+	header := request.Header.Clone()
+	for _, key := range warcRedactedHeaders {
+		if _, ok := header[key]; ok {
+			header.Set(key, "REDACTED")
+		}
+	}
+
+	var requestBlock bytes.Buffer
+	fmt.Fprintf(&requestBlock, "%s %s HTTP/1.1\r\n", request.Method, request.URL.RequestURI())
+	fmt.Fprintf(&requestBlock, "Host: %s\r\n", request.URL.Host)
+	header.Write(&requestBlock)
+	requestBlock.WriteString("\r\n")
+
+	return w.writeRecord([][2]string{
+		{"WARC-Type", "request"},
+		{"WARC-Record-ID", warcRecordID()},
+		{"WARC-Date", date},
+		{"WARC-Target-URI", targetURI},
+		{"WARC-Concurrent-To", responseID},
+		{"Content-Type", "application/http;msgtype=request"},
+	}, requestBlock.Bytes())
+}
+
+// Close closes current WARC file.
+func (w *WARCWriter) Close() error {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// rotate closes current file and starts the next one with warcinfo record.
+func (w *WARCWriter) rotate() error {
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return err
+		}
+	}
+
+	name := fmt.Sprintf("%s-%s-%05d.warc.gz", w.prefix, time.Now().UTC().Format("20060102150405"), w.serial)
+	file, err := os.Create(filepath.Join(w.dir, name))
+	if err != nil {
+		return err
+	}
+	w.serial++
+	w.file = file
+	w.size = 0
+
+	info := []byte("software: github.com/vvelikodny/crawler\r\nformat: WARC File Format 1.1\r\n")
+	return w.writeRecord([][2]string{
+		{"WARC-Type", "warcinfo"},
+		{"WARC-Record-ID", warcRecordID()},
+		{"WARC-Date", time.Now().UTC().Format(warcDateFormat)},
+		{"WARC-Filename", name},
+		{"Content-Type", "application/warc-fields"},
+	}, info)
+}
+
+// writeRecord writes record as separate gzip member.
+func (w *WARCWriter) writeRecord(fields [][2]string, block []byte) error {
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+
+	fmt.Fprintf(zw, "%s\r\n", warcVersion)
+	for _, field := range fields {
+		fmt.Fprintf(zw, "%s: %s\r\n", field[0], field[1])
+	}
+	fmt.Fprintf(zw, "WARC-Block-Digest: %s\r\n", warcDigest(block))
+	fmt.Fprintf(zw, "Content-Length: %s\r\n\r\n", strconv.Itoa(len(block)))
+	zw.Write(block)
+	zw.Write([]byte("\r\n\r\n"))
+
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	n, err := w.file.Write(b.Bytes())
+	w.size += int64(n)
+	return err
+}
+
+func warcDigest(b []byte) string {
+	sum := sha1.Sum(b)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
+
+// warcRecordID returns random UUID v4 URN.
+func warcRecordID() string {
+	var u [16]byte
+	rand.Read(u[:])
+	u[6] = (u[6] & 0x0f) | 0x40
+	u[8] = (u[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", u[0:4], u[4:6], u[6:8], u[8:10], u[10:])
+}
@@ -0,0 +1,141 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testExchange() *http.Response {
+	u, _ := url.Parse("https://velikodny.com/a?b=c")
+	request := &http.Request{Method: http.MethodGet, URL: u, Header: http.Header{"User-Agent": []string{"crawler"}}}
+
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Proto:      "HTTP/1.1",
+		Header:     http.Header{"Content-Type": []string{"text/html"}},
+		Request:    request,
+	}
+}
+
+func readWARC(t *testing.T, path string) string {
+	b, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	assert.NoError(t, err)
+	content, err := ioutil.ReadAll(zr)
+	assert.NoError(t, err)
+
+	return string(content)
+}
+
+func TestWARCWriter_WriteExchange(t *testing.T) {
+	dir, remove := tempDir(t)
+	defer remove()
+	w, err := NewWARCWriter(dir, "test", 0)
+	assert.NoError(t, err)
+
+	assert.NoError(t, w.WriteExchange(testExchange(), []byte("<p>hello</p>")))
+	assert.NoError(t, w.Close())
+
+	files, _ := filepath.Glob(filepath.Join(dir, "test-*-00000.warc.gz"))
+	assert.Equal(t, 1, len(files))
+
+	content := readWARC(t, files[0])
+	records := strings.Split(strings.TrimSuffix(content, "\r\n\r\n"), "\r\n\r\nWARC/1.1\r\n")
+	assert.Equal(t, 3, len(records))
+	assert.Contains(t, records[0], "WARC-Type: warcinfo\r\n")
+
+	assert.Contains(t, records[1], "WARC-Type: response\r\n")
+	assert.Contains(t, records[1], "WARC-Target-URI: https://velikodny.com/a?b=c\r\n")
+	assert.Contains(t, records[1], "WARC-Payload-Digest: "+warcDigest([]byte("<p>hello</p>"))+"\r\n")
+	assert.Contains(t, records[1], "\r\n\r\nHTTP/1.1 200 OK\r\nContent-Type: text/html\r\n\r\n<p>hello</p>")
+
+	assert.Contains(t, records[2], "WARC-Type: request\r\n")
+	assert.Contains(t, records[2], "\r\n\r\nGET /a?b=c HTTP/1.1\r\nHost: velikodny.com\r\nUser-Agent: crawler\r\n\r\n")
+}
+
+func TestWARCWriter_Rollover(t *testing.T) {
+	dir, remove := tempDir(t)
+	defer remove()
+	w, err := NewWARCWriter(dir, "test", 1)
+	assert.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, w.WriteExchange(testExchange(), []byte("body")))
+	}
+	assert.NoError(t, w.Close())
+
+	files, _ := filepath.Glob(filepath.Join(dir, "test-*.warc.gz"))
+	assert.Equal(t, 3, len(files))
+	for _, file := range files {
+		assert.Equal(t, 1, strings.Count(readWARC(t, file), "WARC-Type: response\r\n"))
+	}
+}
+
+func TestWARCWriter_RedactsCredentials(t *testing.T) {
+	dir, remove := tempDir(t)
+	defer remove()
+	w, err := NewWARCWriter(dir, "test", 0)
+	assert.NoError(t, err)
+
+	resp := testExchange()
+	resp.Request.Header.Set("Authorization", "Bearer secret-token")
+	resp.Request.Header.Set("Proxy-Authorization", "Basic c2VjcmV0")
+	resp.Request.Header.Set("Cookie", "session=secret-session")
+	assert.NoError(t, w.WriteExchange(resp, []byte("body")))
+	assert.NoError(t, w.Close())
+
+	files, _ := filepath.Glob(filepath.Join(dir, "test-*.warc.gz"))
+	content := readWARC(t, files[0])
+	assert.NotContains(t, content, "secret")
+	assert.Contains(t, content, "\r\nAuthorization: REDACTED\r\n")
+	assert.Contains(t, content, "\r\nProxy-Authorization: REDACTED\r\n")
+	assert.Contains(t, content, "\r\nCookie: REDACTED\r\n")
+	assert.Contains(t, content, "\r\nUser-Agent: crawler\r\n")
+	// request of response is not changed
+	assert.Equal(t, "Bearer secret-token", resp.Request.Header.Get("Authorization"))
+}
+
+func TestWARCWriter_Redirects(t *testing.T) {
+	dir, remove := tempDir(t)
+	defer remove()
+	w, err := NewWARCWriter(dir, "test", 0)
+	assert.NoError(t, err)
+
+	seed, _ := url.Parse("http://velikodny.com/a?b=c")
+	resp := testExchange()
+	resp.Request.Response = &http.Response{
+		Status:     "301 Moved Permanently",
+		StatusCode: http.StatusMovedPermanently,
+		Proto:      "HTTP/1.1",
+		Header:     http.Header{"Location": []string{"https://velikodny.com/a?b=c"}},
+		Request:    &http.Request{Method: http.MethodGet, URL: seed, Header: http.Header{}},
+	}
+	assert.NoError(t, w.WriteExchange(resp, []byte("<p>hello</p>")))
+	assert.NoError(t, w.Close())
+
+	files, _ := filepath.Glob(filepath.Join(dir, "test-*.warc.gz"))
+	content := readWARC(t, files[0])
+	records := strings.Split(strings.TrimSuffix(content, "\r\n\r\n"), "\r\n\r\nWARC/1.1\r\n")
+	assert.Equal(t, 5, len(records))
+
+	assert.Contains(t, records[1], "WARC-Type: response\r\n")
+	assert.Contains(t, records[1], "WARC-Target-URI: http://velikodny.com/a?b=c\r\n")
+	assert.Contains(t, records[1], "\r\n\r\nHTTP/1.1 301 Moved Permanently\r\nLocation: https://velikodny.com/a?b=c\r\n\r\n")
+	assert.Contains(t, records[2], "WARC-Type: request\r\n")
+	assert.Contains(t, records[2], "WARC-Target-URI: http://velikodny.com/a?b=c\r\n")
+
+	assert.Contains(t, records[3], "WARC-Target-URI: https://velikodny.com/a?b=c\r\n")
+	assert.Contains(t, records[3], "\r\n\r\nHTTP/1.1 200 OK\r\n")
+	assert.Contains(t, records[4], "WARC-Type: request\r\n")
+}
@@ -5,6 +5,7 @@ go 1.14
 require (
 	github.com/deckarep/golang-set v1.7.1
 	github.com/goware/urlx v0.3.1
+	github.com/pmezard/go-difflib v1.0.0
 	github.com/stretchr/testify v1.7.0
 	golang.org/x/net v0.0.0-20210323141857-08027d57d8cf
 )
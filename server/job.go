@@ -0,0 +1,240 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/vvelikodny/crawler/crawler"
+)
+
+const (
+	defaultTimeout = 10 * time.Second
+)
+
+var (
+	ErrInvalidSeed = errors.New("seed must be absolute http or https URL")
+)
+
+// JobStatus is state of crawl job.
+type JobStatus string
+
+const (
+	JobRunning   JobStatus = "running"
+	JobPaused    JobStatus = "paused"
+	JobFinished  JobStatus = "finished"
+	JobCancelled JobStatus = "cancelled"
+	JobFailed    JobStatus = "failed"
+)
+
+// JobRequest holds crawl job options.
+type JobRequest struct {
+	// Seed is URL crawling starts from.
+	Seed string `json:"seed"`
+	// AllowedDomains to crawl, seed host if empty.
+	AllowedDomains []string `json:"allowed_domains,omitempty"`
+	// Concurrency is number of concurrent fetchers, crawler.DefaultConcurrency if not set.
+	Concurrency int `json:"concurrency,omitempty"`
+	// TimeoutMs is request timeout in milliseconds.
+	TimeoutMs int               `json:"timeout_ms,omitempty"`
+	UserAgent string            `json:"user_agent,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	// Links adds discovered links to results.
+	Links bool `json:"links,omitempty"`
+	// Text adds visible text of pages to results.
+	Text bool `json:"text,omitempty"`
+}
+
+// Job describes crawl job.
+type Job struct {
+	ID         string                `json:"id"`
+	Request    *JobRequest           `json:"request"`
+	Status     JobStatus             `json:"status"`
+	Error      string                `json:"error,omitempty"`
+	CreatedAt  time.Time             `json:"created_at"`
+	FinishedAt *time.Time            `json:"finished_at,omitempty"`
+	Stats      *crawler.StatSnapshot `json:"stats"`
+}
+
+// job is running or finished crawl job with its own Crawler and context.
+type job struct {
+	id        string
+	request   *JobRequest
+	createdAt time.Time
+	// results is path of JSON Lines results file
+	results string
+	crawler *crawler.Crawler
+	cancel  context.CancelFunc
+
+	mux        sync.Mutex
+	status     JobStatus
+	err        error
+	finishedAt time.Time
+}
+
+func (r *JobRequest) validate() error {
+	u, err := url.Parse(r.Seed)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidSeed
+	}
+
+	return nil
+}
+
+func (r *JobRequest) options(ctx context.Context) []crawler.Option {
+	allowedDomains := r.AllowedDomains
+	if len(allowedDomains) == 0 {
+		u, _ := url.Parse(r.Seed)
+		allowedDomains = []string{u.Hostname()}
+	}
+
+	timeout := time.Duration(r.TimeoutMs) * time.Millisecond
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	options := []crawler.Option{
+		crawler.WithContext(ctx),
+		crawler.WithClient(&http.Client{Timeout: timeout}),
+		crawler.WithConcurrency(r.Concurrency),
+		crawler.WithAllowedDomains(allowedDomains...),
+	}
+	if r.UserAgent != "" {
+		options = append(options, crawler.WithUserAgent(r.UserAgent))
+	}
+	for key, value := range r.Headers {
+		options = append(options, crawler.WithHeader(key, value))
+	}
+	if r.Text {
+		options = append(options, crawler.WithPageText())
+	}
+
+	return options
+}
+
+// start starts crawling, done is called once crawling is finished.
+func (j *job) start(ctx context.Context, done func()) error {
+	f, err := os.Create(j.results)
+	if err != nil {
+		return err
+	}
+
+	ctx, j.cancel = context.WithCancel(ctx)
+	output := crawler.NewJSONLOutput(f, j.request.Links)
+	c := crawler.New(append(j.request.options(ctx), crawler.WithOutput(output))...)
+	c.OnFetched(func(request *http.Request, response *crawler.Response) {
+		for _, link := range c.Links(request, response) {
+			if !link.IsRejected() {
+				c.VisitLink(link)
+			}
+		}
+	})
+	j.crawler = c
+	j.status = JobRunning
+
+	go func() {
+		defer done()
+
+		err := c.Run(j.request.Seed)
+		c.Wait()
+
+		if closeErr := output.Close(); err == nil {
+			err = closeErr
+		}
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+
+		j.finish(ctx, err)
+	}()
+
+	return nil
+}
+
+func (j *job) finish(ctx context.Context, err error) {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	switch {
+	case err != nil:
+		j.status = JobFailed
+		j.err = err
+	case ctx.Err() != nil:
+		j.status = JobCancelled
+	default:
+		j.status = JobFinished
+	}
+	j.finishedAt = time.Now()
+	j.cancel()
+}
+
+// stop cancels running or paused job.
+func (j *job) stop() error {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	if j.status != JobRunning && j.status != JobPaused {
+		return fmt.Errorf("job %s is %s", j.id, j.status)
+	}
+
+	j.cancel()
+	return nil
+}
+
+// pause pauses running job.
+func (j *job) pause() error {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	if j.status != JobRunning {
+		return fmt.Errorf("job %s is %s", j.id, j.status)
+	}
+
+	j.crawler.Pause()
+	j.status = JobPaused
+	return nil
+}
+
+// resume resumes paused job.
+func (j *job) resume() error {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	if j.status != JobPaused {
+		return fmt.Errorf("job %s is %s", j.id, j.status)
+	}
+
+	j.crawler.Resume()
+	j.status = JobRunning
+	return nil
+}
+
+// view returns job description with current stats.
+func (j *job) view() *Job {
+	stats := j.crawler.Stat().Snapshot()
+
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	view := &Job{
+		ID:        j.id,
+		Request:   j.request,
+		Status:    j.status,
+		CreatedAt: j.createdAt,
+		Stats:     &stats,
+	}
+	if j.err != nil {
+		view.Error = j.err.Error()
+	}
+	if !j.finishedAt.IsZero() {
+		finishedAt := j.finishedAt
+		view.FinishedAt = &finishedAt
+	}
+
+	return view
+}
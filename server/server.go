@@ -0,0 +1,316 @@
+// Package server implements HTTP API to run crawl jobs.
+//
+//	POST /jobs                 submit job, JobRequest in body
+//	GET  /jobs                 list jobs
+//	GET  /jobs/{id}            get job with live stats
+//	POST /jobs/{id}/pause      pause job, fetches in flight are finished
+//	POST /jobs/{id}/resume     resume paused job
+//	POST /jobs/{id}/cancel     cancel job
+//	GET  /jobs/{id}/results    download crawl records as JSON Lines
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+)
+
+// Server runs crawl jobs concurrently, each job has its own Crawler and context.
+type Server struct {
+	// dir stores results of jobs
+	dir    string
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mux  sync.Mutex
+	jobs map[string]*job
+	// ids of jobs in order of submission
+	ids []string
+	// lastID is the highest job id, ids of jobs of previous runs found in dir included
+	lastID int
+	// wg holds running jobs
+	wg sync.WaitGroup
+}
+
+// New creates Server storing results of jobs in dir. Ids of new jobs continue after ids
+// of results found in dir, so results of previous runs are not overwritten.
+func New(dir string) (*Server, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	lastID, err := lastJobID(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		dir:    dir,
+		ctx:    ctx,
+		cancel: cancel,
+		jobs:   make(map[string]*job),
+		lastID: lastID,
+	}, nil
+}
+
+// lastJobID returns the highest id of job results in dir, 0 if there are none.
+func lastJobID(dir string) (int, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	lastID := 0
+	for _, file := range files {
+		id, err := strconv.Atoi(strings.TrimSuffix(file.Name(), ".jsonl"))
+		if err == nil && strings.HasSuffix(file.Name(), ".jsonl") && id > lastID {
+			lastID = id
+		}
+	}
+
+	return lastID, nil
+}
+
+// Close cancels running jobs and waits while they finish.
+func (s *Server) Close() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// Submit starts crawl job.
+func (s *Server) Submit(request *JobRequest) (*Job, error) {
+	if err := request.validate(); err != nil {
+		return nil, err
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.lastID++
+	id := strconv.Itoa(s.lastID)
+	j := &job{
+		id:        id,
+		request:   request,
+		createdAt: time.Now(),
+		results:   filepath.Join(s.dir, id+".jsonl"),
+	}
+
+	s.wg.Add(1)
+	if err := j.start(s.ctx, s.wg.Done); err != nil {
+		s.wg.Done()
+		return nil, err
+	}
+
+	s.jobs[id] = j
+	s.ids = append(s.ids, id)
+
+	return j.view(), nil
+}
+
+// Jobs returns all jobs in order of submission.
+func (s *Server) Jobs() []*Job {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	jobs := make([]*Job, 0, len(s.ids))
+	for _, id := range s.ids {
+		jobs = append(jobs, s.jobs[id].view())
+	}
+
+	return jobs
+}
+
+// Job returns job by id.
+func (s *Server) Job(id string) (*Job, error) {
+	j, err := s.job(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return j.view(), nil
+}
+
+// Pause pauses running job.
+func (s *Server) Pause(id string) error {
+	j, err := s.job(id)
+	if err != nil {
+		return err
+	}
+
+	return j.pause()
+}
+
+// Resume resumes paused job.
+func (s *Server) Resume(id string) error {
+	j, err := s.job(id)
+	if err != nil {
+		return err
+	}
+
+	return j.resume()
+}
+
+// Cancel cancels running or paused job.
+func (s *Server) Cancel(id string) error {
+	j, err := s.job(id)
+	if err != nil {
+		return err
+	}
+
+	return j.stop()
+}
+
+func (s *Server) job(id string) (*job, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	j, ok := s.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("job %s: %w", id, ErrJobNotFound)
+	}
+
+	return j, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+	parts := strings.Split(path, "/")
+	if parts[0] != "jobs" || len(parts) > 3 {
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	switch len(parts) {
+	case 1:
+		s.serveJobs(w, r)
+	case 2:
+		s.serveJob(w, r, parts[1])
+	case 3:
+		s.serveJobAction(w, r, parts[1], parts[2])
+	}
+}
+
+func (s *Server) serveJobs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.Jobs())
+	case http.MethodPost:
+		var request JobRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		job, err := s.Submit(&request)
+		switch {
+		case errors.Is(err, ErrInvalidSeed):
+			writeError(w, http.StatusBadRequest, err)
+		case err != nil:
+			writeError(w, http.StatusInternalServerError, err)
+		default:
+			writeJSON(w, http.StatusCreated, job)
+		}
+	default:
+		writeError(w, http.StatusMethodNotAllowed, errors.New(r.Method+" not allowed"))
+	}
+}
+
+func (s *Server) serveJob(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New(r.Method+" not allowed"))
+		return
+	}
+
+	job, err := s.Job(id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, job)
+}
+
+func (s *Server) serveJobAction(w http.ResponseWriter, r *http.Request, id, action string) {
+	j, err := s.job(id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if action == "results" {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, errors.New(r.Method+" not allowed"))
+			return
+		}
+		serveResults(w, r, j)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errors.New(r.Method+" not allowed"))
+		return
+	}
+
+	switch action {
+	case "cancel":
+		err = j.stop()
+	case "pause":
+		err = j.pause()
+	case "resume":
+		err = j.resume()
+	default:
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	if err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, j.view())
+}
+
+// serveResults serves records written so far, results of running job are partial.
+func serveResults(w http.ResponseWriter, r *http.Request, j *job) {
+	f, err := os.Open(j.results)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="job-%s.jsonl"`, j.id))
+	http.ServeContent(w, r, "", info.ModTime(), f)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
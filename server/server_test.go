@@ -0,0 +1,243 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSite() *httptest.Server {
+	return newBlockingSite(nil, nil)
+}
+
+// newBlockingSite creates site of root page linking to two pages. Requested paths are sent to started
+// and responses are held until release is closed or request is cancelled, nil channels are skipped.
+func newBlockingSite(started chan<- string, release <-chan struct{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if started != nil {
+			started <- r.URL.Path
+		}
+		if release != nil {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body><a href="/a">a</a><a href="/b">b</a></body></html>`)
+	}))
+}
+
+// tempDir creates temporary directory, it's removed with returned func.
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "server")
+	assert.NoError(t, err)
+
+	return dir, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func request(t *testing.T, method, url, body string, v interface{}) int {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	assert.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	if v != nil {
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+	}
+	return resp.StatusCode
+}
+
+// pollJob polls job until cond is met.
+func pollJob(t *testing.T, api string, id string, cond func(job *Job) bool) *Job {
+	for i := 0; i < 100; i++ {
+		var job Job
+		assert.Equal(t, http.StatusOK, request(t, http.MethodGet, api+"/jobs/"+id, "", &job))
+		if cond(&job) {
+			return &job
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	t.Fatalf("job %s is not in expected state", id)
+	return nil
+}
+
+// waitJob polls job until it's finished, cancelled or failed.
+func waitJob(t *testing.T, api string, id string) *Job {
+	return pollJob(t, api, id, func(job *Job) bool {
+		return job.Status != JobRunning && job.Status != JobPaused
+	})
+}
+
+func TestServer_Jobs(t *testing.T) {
+	site := newTestSite()
+	defer site.Close()
+
+	dir, remove := tempDir(t)
+	defer remove()
+	s, err := New(dir)
+	assert.NoError(t, err)
+	defer s.Close()
+	api := httptest.NewServer(s)
+	defer api.Close()
+
+	var job Job
+	assert.Equal(t, http.StatusCreated, request(t, http.MethodPost, api.URL+"/jobs", `{"seed": "`+site.URL+`/", "concurrency": 2}`, &job))
+	assert.Equal(t, "1", job.ID)
+	assert.Equal(t, JobRunning, job.Status)
+
+	finished := waitJob(t, api.URL, job.ID)
+	assert.Equal(t, JobFinished, finished.Status)
+	assert.NotNil(t, finished.FinishedAt)
+	assert.Equal(t, int64(3), finished.Stats.TotalFetched)
+
+	var jobs []*Job
+	assert.Equal(t, http.StatusOK, request(t, http.MethodGet, api.URL+"/jobs", "", &jobs))
+	assert.Equal(t, 1, len(jobs))
+
+	resp, err := http.Get(api.URL + "/jobs/1/results")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+
+	var urls []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var record struct {
+			URL string `json:"url"`
+		}
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		urls = append(urls, record.URL)
+	}
+	assert.ElementsMatch(t, []string{site.URL + "/", site.URL + "/a", site.URL + "/b"}, urls)
+
+	assert.Equal(t, http.StatusConflict, request(t, http.MethodPost, api.URL+"/jobs/1/cancel", "", nil))
+	assert.Equal(t, http.StatusConflict, request(t, http.MethodPost, api.URL+"/jobs/1/pause", "", nil))
+	assert.Equal(t, http.StatusConflict, request(t, http.MethodPost, api.URL+"/jobs/1/resume", "", nil))
+	assert.Equal(t, http.StatusNotFound, request(t, http.MethodPost, api.URL+"/jobs/1/restart", "", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, request(t, http.MethodGet, api.URL+"/jobs/1/pause", "", nil))
+	assert.Equal(t, http.StatusBadRequest, request(t, http.MethodPost, api.URL+"/jobs", `{"seed": "velikodny.com"}`, nil))
+	assert.Equal(t, http.StatusNotFound, request(t, http.MethodGet, api.URL+"/jobs/2", "", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, request(t, http.MethodDelete, api.URL+"/jobs", "", nil))
+}
+
+func TestServer_Cancel(t *testing.T) {
+	started, release := make(chan string, 10), make(chan struct{})
+	site := newBlockingSite(started, release)
+	defer site.Close()
+	defer close(release)
+
+	dir, remove := tempDir(t)
+	defer remove()
+	s, err := New(dir)
+	assert.NoError(t, err)
+	defer s.Close()
+	api := httptest.NewServer(s)
+	defer api.Close()
+
+	var job Job
+	assert.Equal(t, http.StatusCreated, request(t, http.MethodPost, api.URL+"/jobs", `{"seed": "`+site.URL+`/", "concurrency": 1}`, &job))
+	<-started
+	assert.Equal(t, http.StatusOK, request(t, http.MethodPost, api.URL+"/jobs/"+job.ID+"/cancel", "", nil))
+
+	cancelled := waitJob(t, api.URL, job.ID)
+	assert.Equal(t, JobCancelled, cancelled.Status)
+}
+
+func TestServer_PauseResume(t *testing.T) {
+	started, release := make(chan string, 10), make(chan struct{})
+	site := newBlockingSite(started, release)
+	defer site.Close()
+
+	dir, remove := tempDir(t)
+	defer remove()
+	s, err := New(dir)
+	assert.NoError(t, err)
+	defer s.Close()
+	api := httptest.NewServer(s)
+	defer api.Close()
+
+	var job Job
+	assert.Equal(t, http.StatusCreated, request(t, http.MethodPost, api.URL+"/jobs", `{"seed": "`+site.URL+`/", "concurrency": 1}`, &job))
+	assert.Equal(t, "/", <-started)
+	assert.Equal(t, http.StatusOK, request(t, http.MethodPost, api.URL+"/jobs/"+job.ID+"/pause", "", &job))
+	assert.Equal(t, JobPaused, job.Status)
+	assert.Equal(t, http.StatusConflict, request(t, http.MethodPost, api.URL+"/jobs/"+job.ID+"/pause", "", nil))
+
+	// root page in flight is fetched, its links are queued while job is paused
+	close(release)
+	paused := pollJob(t, api.URL, job.ID, func(job *Job) bool {
+		return job.Stats.Queued == 2 && job.Stats.InFlight == 0
+	})
+	assert.Equal(t, JobPaused, paused.Status)
+	assert.Equal(t, int64(1), paused.Stats.TotalFetched)
+	assert.Empty(t, started)
+
+	assert.Equal(t, http.StatusOK, request(t, http.MethodPost, api.URL+"/jobs/"+job.ID+"/resume", "", nil))
+	finished := waitJob(t, api.URL, job.ID)
+	assert.Equal(t, JobFinished, finished.Status)
+	assert.Equal(t, int64(3), finished.Stats.TotalFetched)
+}
+
+func TestServer_CancelPaused(t *testing.T) {
+	started, release := make(chan string, 10), make(chan struct{})
+	site := newBlockingSite(started, release)
+	defer site.Close()
+	defer close(release)
+
+	dir, remove := tempDir(t)
+	defer remove()
+	s, err := New(dir)
+	assert.NoError(t, err)
+	defer s.Close()
+	api := httptest.NewServer(s)
+	defer api.Close()
+
+	var job Job
+	assert.Equal(t, http.StatusCreated, request(t, http.MethodPost, api.URL+"/jobs", `{"seed": "`+site.URL+`/", "concurrency": 1}`, &job))
+	<-started
+	assert.Equal(t, http.StatusOK, request(t, http.MethodPost, api.URL+"/jobs/"+job.ID+"/pause", "", nil))
+	assert.Equal(t, http.StatusOK, request(t, http.MethodPost, api.URL+"/jobs/"+job.ID+"/cancel", "", nil))
+
+	cancelled := waitJob(t, api.URL, job.ID)
+	assert.Equal(t, JobCancelled, cancelled.Status)
+	assert.Equal(t, http.StatusConflict, request(t, http.MethodPost, api.URL+"/jobs/"+job.ID+"/resume", "", nil))
+}
+
+func TestServer_IDsContinueAfterPreviousRun(t *testing.T) {
+	site := newTestSite()
+	defer site.Close()
+
+	dir, remove := tempDir(t)
+	defer remove()
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "7.jsonl"), []byte("previous\n"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "12.txt"), nil, 0644))
+
+	s, err := New(dir)
+	assert.NoError(t, err)
+	defer s.Close()
+
+	job, err := s.Submit(&JobRequest{Seed: site.URL + "/"})
+	assert.NoError(t, err)
+	assert.Equal(t, "8", job.ID)
+
+	previous, err := ioutil.ReadFile(filepath.Join(dir, "7.jsonl"))
+	assert.NoError(t, err)
+	assert.Equal(t, "previous\n", string(previous))
+}